@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/rfomerand/ds_aws/pkg/logtail"
+)
+
+// EC2API is the subset of the EC2 client used here.
+type EC2API interface {
+	DescribeInstances(ctx context.Context, in *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+// LogsAPI is the subset of the CloudWatch Logs client used here.
+type LogsAPI interface {
+	FilterLogEvents(ctx context.Context, in *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+}
+
+// CloudWatchAPI is the subset of the CloudWatch client used here.
+type CloudWatchAPI interface {
+	DescribeAlarms(ctx context.Context, in *cloudwatch.DescribeAlarmsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.DescribeAlarmsOutput, error)
+}
+
+// SecretsAPI is the subset of the Secrets Manager client used here.
+type SecretsAPI interface {
+	GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+type clients struct {
+	ec2        EC2API
+	logs       LogsAPI
+	liveTail   logtail.LiveTailAPI
+	cloudwatch CloudWatchAPI
+	secrets    SecretsAPI
+}
+
+func newClients(ctx context.Context, region string) (*clients, error) {
+	var cfgOpts []func(*config.LoadOptions) error
+	if region != "" {
+		cfgOpts = append(cfgOpts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, cfgOpts...)
+	if err != nil {
+		return nil, err
+	}
+	logs := cloudwatchlogs.NewFromConfig(cfg)
+	return &clients{
+		ec2:        ec2.NewFromConfig(cfg),
+		logs:       logs,
+		liveTail:   logtail.LiveTail(logs),
+		cloudwatch: cloudwatch.NewFromConfig(cfg),
+		secrets:    secretsmanager.NewFromConfig(cfg),
+	}, nil
+}
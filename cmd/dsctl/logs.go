@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rfomerand/ds_aws/pkg/logtail"
+)
+
+// logTarget returns the log group, and the stream name prefix within it,
+// holding a class of logs. Deployments from before the model pull logs got
+// their own group keep both classes in one.
+func (e *env) logTarget(class string) (logtail.Config, error) {
+	var cfg logtail.Config
+	var err error
+	switch class {
+	case "deploy":
+		cfg.Group, err = e.outputs.need("log_group_name")
+		cfg.GroupARN = e.outputs.str("log_group_arn")
+	case "model-pull":
+		if cfg.Group = e.outputs.str("model_pull_log_group_name"); cfg.Group != "" {
+			cfg.GroupARN = e.outputs.str("model_pull_log_group_arn")
+			break
+		}
+		if cfg.Group, err = e.outputs.need("log_group_name"); err != nil {
+			break
+		}
+		cfg.GroupARN = e.outputs.str("log_group_arn")
+		cfg.StreamPrefix, err = e.outputs.need("model_pull_stream")
+	default:
+		err = fmt.Errorf("unknown log class %q; want deploy or model-pull", class)
+	}
+	return cfg, err
+}
+
+func runLogs(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	follow := fs.Bool("follow", false, "keep printing new events until interrupted")
+	live := fs.Bool("live", false, "follow with a CloudWatch Live Tail session instead of polling")
+	since := fs.Duration("since", time.Hour, "print events from this long ago")
+	raw := fs.Bool("raw", false, "print the JSON events as logged")
+	streams := fs.Bool("streams", false, "prefix every line with its log stream, e.g. to tell pool instances apart")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: dsctl logs [flags] [deploy|model-pull]")
+		fs.PrintDefaults()
+	}
+	// Flags may come after the class too
+	var classes []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		classes = append(classes, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	class := "deploy"
+	switch len(classes) {
+	case 0:
+	case 1:
+		class = classes[0]
+	default:
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, err := e.logTarget(class)
+	if err != nil {
+		return err
+	}
+	c, err := e.aws(ctx)
+	if err != nil {
+		return err
+	}
+	cfg.Start = e.now().Add(-*since)
+	cfg.Follow = *follow || *live
+	cfg.PollInterval = e.pollInterval
+	if *live {
+		cfg.Live = c.liveTail
+	}
+
+	tail, err := logtail.Start(ctx, c.logs, cfg)
+	if err != nil {
+		return err
+	}
+	for ev := range tail.Events() {
+		fmt.Fprintln(e.stdout, formatEvent(ev, *raw, *streams))
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return tail.Err()
+}
+
+// logEvent holds the fields of the JSON events the bootstrap logs that are
+// worth showing on a terminal.
+type logEvent struct {
+	Timestamp string `json:"timestamp"`
+	Stage     string `json:"stage"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+}
+
+func formatEvent(ev logtail.Event, raw, streams bool) string {
+	line := strings.TrimRight(ev.Message, "\n")
+	var parsed logEvent
+	if !raw && json.Unmarshal([]byte(line), &parsed) == nil && parsed.Message != "" {
+		line = fmt.Sprintf("%s %-5s %s: %s", parsed.Timestamp, strings.ToUpper(parsed.Level), parsed.Stage, parsed.Message)
+	}
+	if streams {
+		line = ev.Stream + " " + line
+	}
+	return line
+}
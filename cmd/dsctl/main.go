@@ -0,0 +1,183 @@
+// Command dsctl operates a deployment, finding everything it needs in the
+// outputs of its Terraform configuration:
+//
+//	go build -o dsctl ./cmd/dsctl
+//	dsctl -chdir ../my-deployment status
+//	dsctl logs model-pull -follow
+//	dsctl ssh
+//	dsctl tunnel
+//	dsctl models pull llama3.2
+//	dsctl open
+//
+// Outputs are read with terraform output -json, or from a saved copy with
+// -outputs. Logs come from CloudWatch, so they can be read without SSH
+// access to the instance.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errUsage is returned by commands that printed their usage; it maps to
+// exit status 2.
+var errUsage = errors.New("usage")
+
+type command struct {
+	name, args, summary string
+	run                 func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = []command{
+	{"status", "", "show the instances, services, last model pull and alarms", runStatus},
+	{"logs", "[deploy|model-pull] [-follow]", "print bootstrap or model pull logs from CloudWatch", runLogs},
+	{"ssh", "[command...]", "open a shell, or run a command, on the instance", runSSH},
+	{"tunnel", "", "forward OpenWebUI and Ollama to localhost over SSH", runTunnel},
+	{"models", "list|pull|rm [model...]", "manage the models on the Ollama host", runModels},
+	{"open", "[webui|logs]", "open OpenWebUI or the CloudWatch log group in a browser", runOpen},
+}
+
+// env is what every command runs against. Tests replace the AWS clients,
+// the HTTP client and the program runner.
+type env struct {
+	stdout, stderr io.Writer
+	outputs        outputs
+	// dir is the Terraform configuration directory; relative key paths in
+	// the outputs are relative to it.
+	dir    string
+	region string
+
+	newClients func(ctx context.Context, region string) (*clients, error)
+	clients    *clients
+	httpClient *http.Client
+	// exec runs an interactive program such as ssh to completion.
+	exec         func(ctx context.Context, name string, args ...string) error
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+// aws returns the AWS clients, creating them on first use so that commands
+// which do not need them work without AWS credentials.
+func (e *env) aws(ctx context.Context) (*clients, error) {
+	if e.clients == nil {
+		c, err := e.newClients(ctx, e.region)
+		if err != nil {
+			return nil, err
+		}
+		e.clients = c
+	}
+	return e.clients, nil
+}
+
+// path resolves p, a path from the outputs, against the configuration
+// directory.
+func (e *env) path(p string) string {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(e.dir, p)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dsctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("chdir", ".", "Terraform configuration directory of the deployment")
+	outputsFile := fs.String("outputs", "", "read terraform output -json from this file (- for stdin) instead of running terraform")
+	region := fs.String("region", "", "AWS region (default the region output)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: dsctl [flags] command [args]")
+		fmt.Fprintln(stderr, "\ncommands:")
+		for _, c := range commands {
+			fmt.Fprintf(stderr, "  %-8s %-30s %s\n", c.name, c.args, c.summary)
+		}
+		fmt.Fprintln(stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == fs.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "dsctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	out, err := loadOutputs(ctx, *dir, *outputsFile, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "dsctl:", err)
+		return 1
+	}
+	e := &env{
+		stdout:       stdout,
+		stderr:       stderr,
+		outputs:      out,
+		dir:          *dir,
+		region:       *region,
+		newClients:   newClients,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		exec:         execProgram(stdin, stdout, stderr),
+		pollInterval: 2 * time.Second,
+		now:          time.Now,
+	}
+	if e.region == "" {
+		e.region = out.str("region")
+	}
+	return exitStatus(cmd.run(ctx, e, fs.Args()[1:]), stderr)
+}
+
+func exitStatus(err error, stderr io.Writer) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintln(stderr, "dsctl:", err)
+		return 1
+	}
+}
+
+// execProgram runs programs attached to the terminal. An interrupt ends the
+// program through the terminal rather than the context, so ssh can clean up.
+func execProgram(stdin io.Reader, stdout, stderr io.Writer) func(ctx context.Context, name string, args ...string) error {
+	return func(ctx context.Context, name string, args ...string) error {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rfomerand/ds_aws/pkg/ollama"
+)
+
+// ollamaFlags select and authenticate the Ollama endpoint.
+type ollamaFlags struct {
+	url, token, basicUser string
+	insecure              bool
+}
+
+func (f *ollamaFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.url, "url", "", "Ollama URL, e.g. http://localhost:11434 through dsctl tunnel (default the ollama_api_url output)")
+	fs.StringVar(&f.token, "api-token", os.Getenv("OLLAMA_API_TOKEN"), "API proxy token or password (default $OLLAMA_API_TOKEN, else read from the api_proxy_secret_arn secret)")
+	fs.StringVar(&f.basicUser, "basic-user", "", "API proxy user when it runs in basic auth mode")
+	fs.BoolVar(&f.insecure, "insecure", false, "skip TLS verification, for the proxy's self-signed certificate")
+}
+
+// ollama returns a client for the deployment's Ollama API. The API proxy
+// credential is only looked up when the default URL is used, since an
+// explicit one usually points at a tunnel that bypasses the proxy.
+func (e *env) ollama(ctx context.Context, f ollamaFlags) (*ollama.Client, error) {
+	baseURL, token := f.url, f.token
+	if baseURL == "" {
+		var err error
+		if baseURL, err = e.outputs.need("ollama_api_url"); err != nil {
+			return nil, err
+		}
+		if arn := e.outputs.str("api_proxy_secret_arn"); token == "" && arn != "" {
+			if token, err = e.secret(ctx, arn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	opts := []ollama.Option{ollama.WithRetries(2, time.Second)}
+	if f.insecure {
+		opts = append(opts, ollama.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}))
+	}
+	switch {
+	case token != "" && f.basicUser != "":
+		opts = append(opts, ollama.WithBasicAuth(f.basicUser, token))
+	case token != "":
+		opts = append(opts, ollama.WithBearerToken(token))
+	}
+	return ollama.NewClient(baseURL, opts...)
+}
+
+func runModels(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("models", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var of ollamaFlags
+	of.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: dsctl models [flags] list|pull|rm [model...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	action, names := fs.Arg(0), fs.Args()[1:]
+
+	switch action {
+	case "list":
+		if len(names) > 0 {
+			fs.Usage()
+			return errUsage
+		}
+	case "pull":
+		if len(names) == 0 {
+			model, err := e.outputs.need("ollama_model")
+			if err != nil {
+				return err
+			}
+			names = []string{model}
+		}
+	case "rm":
+		if len(names) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+	default:
+		fs.Usage()
+		return errUsage
+	}
+
+	client, err := e.ollama(ctx, of)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "list":
+		return listModels(ctx, e, client)
+	case "pull":
+		for _, name := range names {
+			if err := pullModel(ctx, e, client, name); err != nil {
+				return err
+			}
+		}
+	case "rm":
+		for _, name := range names {
+			if err := client.Delete(ctx, name); err != nil {
+				return fmt.Errorf("delete %s: %w", name, err)
+			}
+			fmt.Fprintf(e.stdout, "Deleted %s\n", name)
+		}
+	}
+	return nil
+}
+
+func listModels(ctx context.Context, e *env, client *ollama.Client) error {
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		return fmt.Errorf("list models: %w", err)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPARAMETERS\tQUANTIZATION\tSIZE\tMODIFIED")
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Name, m.Details.ParameterSize, m.Details.QuantizationLevel,
+			formatBytes(m.Size), m.ModifiedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+// pullModel pulls name, printing each new status and download progress in
+// steps of 10%.
+func pullModel(ctx context.Context, e *env, client *ollama.Client, name string) error {
+	lastStatus, lastStep := "", -1
+	err := client.Pull(ctx, name, func(p ollama.PullProgress) error {
+		step := -1
+		if pct := p.Percent(); pct >= 0 {
+			step = int(pct) / 10
+		}
+		if p.Status == lastStatus && step == lastStep {
+			return nil
+		}
+		lastStatus, lastStep = p.Status, step
+		if step >= 0 {
+			fmt.Fprintf(e.stderr, "%s: %s %d%% of %s\n", name, p.Status, step*10, formatBytes(p.Total))
+		} else {
+			fmt.Fprintf(e.stderr, "%s: %s\n", name, p.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("pull %s: %w", name, err)
+	}
+	fmt.Fprintf(e.stdout, "Pulled %s\n", name)
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
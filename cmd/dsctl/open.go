@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+)
+
+func runOpen(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: dsctl open [webui|logs]")
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	target := "webui"
+	if fs.NArg() > 0 {
+		target = fs.Arg(0)
+	}
+	var output string
+	switch {
+	case fs.NArg() > 1:
+		fs.Usage()
+		return errUsage
+	case target == "webui":
+		output = "openwebui_url"
+	case target == "logs":
+		output = "cloudwatch_logs_url"
+	default:
+		fs.Usage()
+		return errUsage
+	}
+	u, err := e.outputs.need(output)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, u)
+	switch runtime.GOOS {
+	case "darwin":
+		return e.exec(ctx, "open", u)
+	case "windows":
+		return e.exec(ctx, "rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		return e.exec(ctx, "xdg-open", u)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// outputs holds the values of the root module's outputs by name.
+type outputs map[string]json.RawMessage
+
+// loadOutputs reads the outputs from file, or from stdin when file is "-",
+// and otherwise runs terraform output -json in dir.
+func loadOutputs(ctx context.Context, dir, file string, stdin io.Reader) (outputs, error) {
+	var raw []byte
+	var err error
+	switch file {
+	case "":
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "terraform", "-chdir="+dir, "output", "-json")
+		cmd.Stderr = &stderr
+		if raw, err = cmd.Output(); err != nil {
+			return nil, fmt.Errorf("terraform output: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	case "-":
+		raw, err = io.ReadAll(stdin)
+	default:
+		raw, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseOutputs(raw)
+}
+
+func parseOutputs(raw []byte) (outputs, error) {
+	var all map[string]struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, fmt.Errorf("decode outputs: %w", err)
+	}
+	if len(all) == 0 {
+		return nil, errors.New("no outputs found; has the deployment been applied?")
+	}
+	out := outputs{}
+	for name, o := range all {
+		out[name] = o.Value
+	}
+	return out, nil
+}
+
+// str returns a string output, or "" when it is missing or not a string.
+func (o outputs) str(name string) string {
+	var s string
+	if json.Unmarshal(o[name], &s) != nil {
+		return ""
+	}
+	return s
+}
+
+// list returns a list of strings output, or nil when it is missing or of
+// another type.
+func (o outputs) list(name string) []string {
+	var l []string
+	if json.Unmarshal(o[name], &l) != nil {
+		return nil
+	}
+	return l
+}
+
+// need returns a string output that must be set.
+func (o outputs) need(name string) (string, error) {
+	if s := o.str(name); s != "" {
+		return s, nil
+	}
+	return "", fmt.Errorf("output %s is empty or missing; is the deployment up to date?", name)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// sshUser is the login user of the Ubuntu AMIs the module runs on.
+const sshUser = "ubuntu"
+
+// host returns the address users are given, taken from openwebui_url. In
+// autoscaling mode without an Elastic IP there is none, and the public IP of
+// the group's running instance is looked up instead.
+func (e *env) host(ctx context.Context) (string, error) {
+	if u, err := url.Parse(e.outputs.str("openwebui_url")); err == nil && u.Hostname() != "" {
+		return u.Hostname(), nil
+	}
+	group := e.outputs.str("autoscaling_group_name")
+	if group == "" {
+		return "", errors.New("the outputs hold no address for the instance")
+	}
+	c, err := e.aws(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := c.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("tag:aws:autoscaling:groupName"), Values: []string{group}},
+			{Name: aws.String("instance-state-name"), Values: []string{"running"}},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("describe instances: %w", err)
+	}
+	for _, r := range out.Reservations {
+		for _, i := range r.Instances {
+			if ip := aws.ToString(i.PublicIpAddress); ip != "" {
+				return ip, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no running instance with a public IP in %s", group)
+}
+
+// secret returns the value of a Secrets Manager secret.
+func (e *env) secret(ctx context.Context, arn string) (string, error) {
+	c, err := e.aws(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := c.secrets.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(arn)})
+	if err != nil {
+		return "", fmt.Errorf("get secret %s: %w", arn, err)
+	}
+	return aws.ToString(out.SecretString), nil
+}
+
+// identity returns the private key file to pass to ssh -i, or "" to leave
+// it to the SSH agent and configuration. A generated key kept in Secrets
+// Manager is fetched into the file first, as the ssh_command output does.
+func (e *env) identity(ctx context.Context) (string, error) {
+	path := e.outputs.str("ssh_private_key_path")
+	if path == "" {
+		return "", nil
+	}
+	path = e.path(path)
+	arn := e.outputs.str("ssh_private_key_secret_arn")
+	if arn == "" {
+		return path, nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	key, err := e.secret(ctx, arn)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// sshArgs returns the ssh arguments that log in to the instance, followed
+// by extra.
+func (e *env) sshArgs(ctx context.Context, extra ...string) ([]string, error) {
+	host, err := e.host(ctx)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := e.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	if identity != "" {
+		args = append(args, "-i", identity)
+	}
+	args = append(args, extra...)
+	return append(args, sshUser+"@"+host), nil
+}
+
+func runSSH(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("ssh", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: dsctl ssh [command...]")
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	sshArgs, err := e.sshArgs(ctx)
+	if err != nil {
+		return err
+	}
+	return e.exec(ctx, "ssh", append(sshArgs, fs.Args()...)...)
+}
+
+func runTunnel(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("tunnel", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	webuiPort := fs.Int("webui-port", 8080, "local port forwarded to OpenWebUI")
+	ollamaPort := fs.Int("ollama-port", 11434, "local port forwarded to Ollama, behind the API proxy if any")
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: dsctl tunnel [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	sshArgs, err := e.sshArgs(ctx,
+		"-N", "-o", "ExitOnForwardFailure=yes",
+		"-L", fmt.Sprintf("%d:localhost:8080", *webuiPort),
+		"-L", fmt.Sprintf("%d:localhost:11434", *ollamaPort),
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "OpenWebUI at http://localhost:%d, Ollama at http://localhost:%d; press Ctrl-C to stop\n", *webuiPort, *ollamaPort)
+	return e.exec(ctx, "ssh", sshArgs...)
+}
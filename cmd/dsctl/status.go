@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/rfomerand/ds_aws/pkg/logtail"
+)
+
+// pullResultWindow is how far back status looks for the outcome of the last
+// model pull.
+const pullResultWindow = 7 * 24 * time.Hour
+
+// pullResult is the model_pull_result event the models stage logs last.
+type pullResult struct {
+	Timestamp   string  `json:"timestamp"`
+	Status      string  `json:"status"`
+	Model       string  `json:"model"`
+	Source      string  `json:"source"`
+	Reason      string  `json:"reason"`
+	PullSeconds float64 `json:"pull_seconds"`
+}
+
+// check is one line of the status report.
+type check struct {
+	name, detail string
+	ok           bool
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	var of ollamaFlags
+	of.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: dsctl status [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	deployment, err := e.outputs.need("deployment_id")
+	if err != nil {
+		return err
+	}
+	c, err := e.aws(ctx)
+	if err != nil {
+		return err
+	}
+
+	checks := instanceChecks(ctx, c.ec2, deployment)
+	checks = append(checks, e.webUICheck(ctx), e.ollamaCheck(ctx, of))
+	checks = append(checks, e.pullCheck(ctx, c.logs), alarmCheck(ctx, c.cloudwatch, e.outputs.list("error_alarm_names")))
+
+	fmt.Fprintf(e.stdout, "Deployment %s in %s\n\n", deployment, e.region)
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, ch := range checks {
+		state := "ok"
+		if !ch.ok {
+			state = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", ch.name, state, ch.detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// instanceChecks reports every instance tagged with the deployment, failing
+// unless it is running.
+func instanceChecks(ctx context.Context, api EC2API, deployment string) []check {
+	in := &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("tag:Deployment"), Values: []string{deployment}},
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	}
+	var checks []check
+	for {
+		out, err := api.DescribeInstances(ctx, in)
+		if err != nil {
+			return append(checks, check{name: "Instances", detail: err.Error()})
+		}
+		for _, r := range out.Reservations {
+			for _, i := range r.Instances {
+				name := aws.ToString(i.InstanceId)
+				for _, tag := range i.Tags {
+					if aws.ToString(tag.Key) == "Name" {
+						name = aws.ToString(tag.Value)
+					}
+				}
+				state := string(i.State.Name)
+				detail := fmt.Sprintf("%s %s %s", aws.ToString(i.InstanceId), i.InstanceType, state)
+				if ip := aws.ToString(i.PublicIpAddress); ip != "" {
+					detail += " " + ip
+				}
+				checks = append(checks, check{name: name, detail: detail, ok: i.State.Name == types.InstanceStateNameRunning})
+			}
+		}
+		if out.NextToken == nil {
+			break
+		}
+		in.NextToken = out.NextToken
+	}
+	if len(checks) == 0 {
+		return []check{{name: "Instances", detail: "no instance is tagged Deployment=" + deployment}}
+	}
+	return checks
+}
+
+func (e *env) webUICheck(ctx context.Context) check {
+	ch := check{name: "OpenWebUI"}
+	u, err := e.outputs.need("openwebui_url")
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	resp.Body.Close()
+	ch.ok = resp.StatusCode < 500
+	ch.detail = fmt.Sprintf("%s answered %s", u, resp.Status)
+	return ch
+}
+
+func (e *env) ollamaCheck(ctx context.Context, of ollamaFlags) check {
+	ch := check{name: "Ollama"}
+	client, err := e.ollama(ctx, of)
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	version, err := client.Version(ctx)
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	ch.detail = "version " + version
+	model := e.outputs.str("ollama_model")
+	if model == "" {
+		ch.ok = true
+		return ch
+	}
+	ok, err := client.HasModel(ctx, model)
+	switch {
+	case err != nil:
+		ch.detail += ", " + err.Error()
+	case ok:
+		ch.ok = true
+		ch.detail += ", " + model + " present"
+	default:
+		ch.detail += ", " + model + " missing"
+	}
+	return ch
+}
+
+// pullCheck reports the last model_pull_result event. No event at all is
+// not a failure: the pull may still be running.
+func (e *env) pullCheck(ctx context.Context, api LogsAPI) check {
+	ch := check{name: "Model pull"}
+	cfg, err := e.logTarget("model-pull")
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	cfg.FilterPattern = `{ $.event = "model_pull_result" }`
+	cfg.Start = e.now().Add(-pullResultWindow)
+	tail, err := logtail.Start(ctx, api, cfg)
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	var last *pullResult
+	var lastTimestamp time.Time
+	for ev := range tail.Events() {
+		var r pullResult
+		if json.Unmarshal([]byte(ev.Message), &r) != nil {
+			continue
+		}
+		if last == nil || !ev.Timestamp.Before(lastTimestamp) {
+			last, lastTimestamp = &r, ev.Timestamp
+		}
+	}
+	if err := tail.Err(); err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+
+	switch {
+	case last == nil:
+		ch.ok = true
+		ch.detail = "no result in the last week; see dsctl logs model-pull"
+	case last.Status == "ready":
+		ch.ok = true
+		ch.detail = fmt.Sprintf("%s ready from %s in %.0fs at %s", last.Model, last.Source, last.PullSeconds, last.Timestamp)
+	default:
+		ch.detail = fmt.Sprintf("%s %s at %s: %s", last.Model, last.Status, last.Timestamp, last.Reason)
+	}
+	return ch
+}
+
+// alarmCheck fails when any of the deployment's error alarms is firing.
+func alarmCheck(ctx context.Context, api CloudWatchAPI, names []string) check {
+	ch := check{name: "Alarms"}
+	if len(names) == 0 {
+		ch.ok = true
+		ch.detail = "none configured"
+		return ch
+	}
+	out, err := api.DescribeAlarms(ctx, &cloudwatch.DescribeAlarmsInput{AlarmNames: names})
+	if err != nil {
+		ch.detail = err.Error()
+		return ch
+	}
+	var firing []string
+	for _, a := range out.MetricAlarms {
+		if a.StateValue == cwtypes.StateValueAlarm {
+			firing = append(firing, aws.ToString(a.AlarmName))
+		}
+	}
+	if len(firing) > 0 {
+		ch.detail = "in alarm: " + strings.Join(firing, ", ")
+		return ch
+	}
+	ch.ok = true
+	ch.detail = fmt.Sprintf("%d alarms, none firing", len(out.MetricAlarms))
+	return ch
+}
@@ -0,0 +1,156 @@
+// Command modelsnapshot snapshots the model volume of a deployment whose
+// models are fully pulled, tagging it with the model list and Ollama version:
+//
+//	go run ./cmd/modelsnapshot \
+//	    -deployment "$(terraform output -raw deployment_id)" \
+//	    -ollama-url "$(terraform output -raw ollama_api_url)" -wait
+//
+// Later deployments restore it with model_volume_snapshot_tags, e.g.
+// { Purpose = "ollama-models" }, or pin it with model_volume_snapshot_id.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"github.com/rfomerand/ds_aws/pkg/ollama"
+	"github.com/rfomerand/ds_aws/pkg/snapshot"
+)
+
+type tagFlags map[string]string
+
+func (t tagFlags) String() string { return fmt.Sprint(map[string]string(t)) }
+
+func (t tagFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return errors.New("want key=value")
+	}
+	t[k] = v
+	return nil
+}
+
+type options struct {
+	region, deployment, volumeID string
+	ollamaURL, token, basicUser  string
+	insecure, wait               bool
+	maxWait                      time.Duration
+	tags                         map[string]string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	opts := options{tags: tagFlags{}}
+	fs := flag.NewFlagSet("modelsnapshot", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.region, "region", "", "AWS region (default from the AWS config)")
+	fs.StringVar(&opts.deployment, "deployment", "", "deployment_id output of the module; used to find the model volume")
+	fs.StringVar(&opts.volumeID, "volume-id", "", "model volume to snapshot instead of looking it up by deployment")
+	fs.StringVar(&opts.ollamaURL, "ollama-url", "", "ollama_api_url output of the module")
+	fs.StringVar(&opts.token, "api-token", os.Getenv("OLLAMA_API_TOKEN"), "API proxy token or password (default $OLLAMA_API_TOKEN)")
+	fs.StringVar(&opts.basicUser, "basic-user", "", "API proxy user when it runs in basic auth mode")
+	fs.BoolVar(&opts.insecure, "insecure", false, "skip TLS verification, for the proxy's self-signed certificate")
+	fs.BoolVar(&opts.wait, "wait", false, "wait for the snapshot to complete")
+	fs.DurationVar(&opts.maxWait, "max-wait", 4*time.Hour, "how long -wait waits")
+	fs.Var(tagFlags(opts.tags), "tag", "extra key=value tag, repeatable")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: modelsnapshot -ollama-url URL (-deployment ID | -volume-id ID) [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if opts.ollamaURL == "" || (opts.deployment == "" && opts.volumeID == "") {
+		fs.Usage()
+		return 2
+	}
+
+	if err := snapshotModels(context.Background(), stdout, opts); err != nil {
+		fmt.Fprintln(stderr, "modelsnapshot:", err)
+		return 1
+	}
+	return 0
+}
+
+func snapshotModels(ctx context.Context, stdout io.Writer, o options) error {
+	clientOpts := []ollama.Option{ollama.WithRetries(3, 2*time.Second)}
+	if o.insecure {
+		clientOpts = append(clientOpts, ollama.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}))
+	}
+	switch {
+	case o.token != "" && o.basicUser != "":
+		clientOpts = append(clientOpts, ollama.WithBasicAuth(o.basicUser, o.token))
+	case o.token != "":
+		clientOpts = append(clientOpts, ollama.WithBearerToken(o.token))
+	}
+	client, err := ollama.NewClient(o.ollamaURL, clientOpts...)
+	if err != nil {
+		return err
+	}
+
+	version, err := client.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("ollama version: %w", err)
+	}
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		return fmt.Errorf("list models: %w", err)
+	}
+	var names []string
+	for _, m := range models {
+		names = append(names, m.Name)
+	}
+
+	var cfgOpts []func(*config.LoadOptions) error
+	if o.region != "" {
+		cfgOpts = append(cfgOpts, config.WithRegion(o.region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, cfgOpts...)
+	if err != nil {
+		return err
+	}
+	api := ec2.NewFromConfig(cfg)
+
+	volumeID := o.volumeID
+	if volumeID == "" {
+		if volumeID, err = snapshot.FindVolume(ctx, api, o.deployment); err != nil {
+			return err
+		}
+	}
+
+	id, err := snapshot.Create(ctx, api, snapshot.Request{
+		VolumeID:      volumeID,
+		DeploymentID:  o.deployment,
+		Models:        names,
+		OllamaVersion: version,
+		Tags:          o.tags,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Started %s of %s with %d model(s), Ollama %s\n", id, volumeID, len(names), version)
+
+	if o.wait {
+		if err := snapshot.Wait(ctx, api, id, o.maxWait); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s completed\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Command plancheck evaluates the policy guardrails against a Terraform plan:
+//
+//	terraform plan -out plan.tfplan
+//	terraform show -json plan.tfplan > plan.json
+//	go run ./cmd/plancheck -format sarif -out plancheck.sarif plan.json
+//
+// It exits 1 when any error-severity rule fails.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rfomerand/ds_aws/pkg/policy"
+)
+
+type intList []int
+
+func (l *intList) String() string { return fmt.Sprint([]int(*l)) }
+
+func (l *intList) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		*l = append(*l, n)
+	}
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	defaults := policy.DefaultConfig()
+
+	fs := flag.NewFlagSet("plancheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "report format: text, json or sarif")
+	out := fs.String("out", "", "write the report to this file instead of stdout")
+	var allowPorts intList
+	fs.Var(&allowPorts, "allow-port", fmt.Sprintf("port that may be open to the world, repeatable (default %v)", defaults.AllowedIngressPorts))
+	minRetention := fs.Int("min-retention", defaults.MinLogRetentionDays, "minimum CloudWatch log retention in days")
+	requireTags := fs.String("require-tags", strings.Join(defaults.RequiredTags, ","), "comma-separated tags every taggable resource must carry")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: plancheck [flags] [plan.json|-]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := policy.Config{
+		AllowedIngressPorts: defaults.AllowedIngressPorts,
+		MinLogRetentionDays: *minRetention,
+	}
+	if len(allowPorts) > 0 {
+		cfg.AllowedIngressPorts = allowPorts
+	}
+	for _, tag := range strings.Split(*requireTags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cfg.RequiredTags = append(cfg.RequiredTags, tag)
+		}
+	}
+
+	var plan *policy.Plan
+	var err error
+	if path := fs.Arg(0); path != "" && path != "-" {
+		plan, err = policy.LoadPlanFile(path)
+	} else {
+		plan, err = policy.LoadPlan(stdin)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "plancheck:", err)
+		return 2
+	}
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, "plancheck:", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+
+	report := policy.Evaluate(plan, cfg)
+	switch *format {
+	case "text":
+		err = report.WriteText(w)
+	case "json":
+		err = report.WriteJSON(w)
+	case "sarif":
+		err = report.WriteSARIF(w)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "plancheck:", err)
+		return 2
+	}
+
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
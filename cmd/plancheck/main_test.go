@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testdata = "../../pkg/policy/testdata/"
+
+func runPlancheck(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// ruleIDs returns the rules of the findings in a JSON report.
+func ruleIDs(t *testing.T, report string) []string {
+	t.Helper()
+	var decoded struct {
+		Findings []struct {
+			RuleID string `json:"rule_id"`
+		} `json:"findings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(report), &decoded))
+	seen := map[string]bool{}
+	var ids []string
+	for _, f := range decoded.Findings {
+		if !seen[f.RuleID] {
+			seen[f.RuleID] = true
+			ids = append(ids, f.RuleID)
+		}
+	}
+	return ids
+}
+
+func TestCompliantPlanExitsZero(t *testing.T) {
+	code, stdout, stderr := runPlancheck(t, "", testdata+"compliant.plan.json")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "No policy violations in 7 resources.\n", stdout)
+}
+
+func TestViolationsExitOne(t *testing.T) {
+	plan, err := os.ReadFile(testdata + "violations.plan.json")
+	require.NoError(t, err)
+
+	code, stdout, _ := runPlancheck(t, string(plan), "-format", "json", "-")
+	assert.Equal(t, 1, code)
+	assert.ElementsMatch(t, []string{"SG001", "EBS001", "EC2001", "IAM001", "LOG001"}, ruleIDs(t, stdout))
+}
+
+func TestFlagsTuneTheRules(t *testing.T) {
+	code, stdout, _ := runPlancheck(t, "", "-format", "json", testdata+"baseline.plan.json")
+	assert.Equal(t, 1, code)
+	assert.ElementsMatch(t, []string{"SG001", "EBS001", "EC2001", "TAG001"}, ruleIDs(t, stdout))
+
+	code, stdout, _ = runPlancheck(t, "",
+		"-format", "json", "-allow-port", "22,8080", "-allow-port", "11434", "-require-tags", "", "-min-retention", "90",
+		testdata+"baseline.plan.json")
+	assert.Equal(t, 1, code)
+	assert.ElementsMatch(t, []string{"EBS001", "EC2001", "LOG001"}, ruleIDs(t, stdout))
+}
+
+// Warnings are reported but do not fail the check.
+func TestWarningsExitZero(t *testing.T) {
+	code, stdout, _ := runPlancheck(t, "", "-require-tags", "Environment,Owner", testdata+"compliant.plan.json")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "missing tags: Owner")
+}
+
+func TestWritesReportToFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "plancheck.sarif")
+	code, stdout, _ := runPlancheck(t, "", "-format", "sarif", "-out", out, testdata+"violations.plan.json")
+	assert.Equal(t, 1, code)
+	assert.Empty(t, stdout)
+
+	report, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var log struct {
+		Version string `json:"version"`
+	}
+	require.NoError(t, json.Unmarshal(report, &log))
+	assert.Equal(t, "2.1.0", log.Version)
+}
+
+func TestUsageErrorsExitTwo(t *testing.T) {
+	cases := map[string]struct {
+		stdin  string
+		args   []string
+		stderr string
+	}{
+		"unknown format": {"", []string{"-format", "xml", testdata + "compliant.plan.json"}, `unknown format "xml"`},
+		"missing plan":   {"", []string{testdata + "missing.plan.json"}, "no such file"},
+		"not a plan":     {`{"values": {}}`, nil, "format_version"},
+		"two plans":      {"", []string{"a.json", "b.json"}, "usage: plancheck"},
+		"bad port":       {"", []string{"-allow-port", "ssh"}, "invalid value"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, _, stderr := runPlancheck(t, tc.stdin, tc.args...)
+			assert.Equal(t, 2, code)
+			assert.Contains(t, stderr, tc.stderr)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+# The Ollama API behind the authenticating proxy with a Let's Encrypt
+# certificate, on a hostname that survives instance rebuilds.
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.aws_region
+}
+
+module "ds_aws" {
+  source = "../.."
+
+  github_token        = var.github_token
+  ssh_public_key_path = var.ssh_public_key_path
+  instance_type       = "r6i.4xlarge"
+  ollama_model        = "deepseek-r1:14b"
+
+  enable_api_proxy     = true
+  api_proxy_tls_mode   = "acme"
+  api_proxy_domain     = var.hostname
+  api_proxy_acme_email = var.acme_email
+
+  create_eip      = true
+  route53_zone_id = var.route53_zone_id
+  dns_hostname    = var.hostname
+}
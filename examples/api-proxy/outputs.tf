@@ -0,0 +1,12 @@
+output "openwebui_url" {
+  value = module.ds_aws.openwebui_url
+}
+
+output "ollama_api_url" {
+  value = module.ds_aws.ollama_api_url
+}
+
+output "api_proxy_secret_arn" {
+  description = "Fetch the API token with: aws secretsmanager get-secret-value --secret-id <arn>"
+  value       = module.ds_aws.api_proxy_secret_arn
+}
@@ -0,0 +1,24 @@
+# A single instance running OpenWebUI and Ollama with the module defaults,
+# apart from a smaller instance and model.
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.aws_region
+}
+
+module "ds_aws" {
+  source = "../.."
+
+  github_token        = var.github_token
+  ssh_public_key_path = var.ssh_public_key_path
+  instance_type       = "r6i.4xlarge"
+  ollama_model        = "deepseek-r1:14b"
+}
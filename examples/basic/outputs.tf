@@ -0,0 +1,11 @@
+output "openwebui_url" {
+  value = module.ds_aws.openwebui_url
+}
+
+output "ollama_api_url" {
+  value = module.ds_aws.ollama_api_url
+}
+
+output "ssh_command" {
+  value = module.ds_aws.ssh_command
+}
@@ -0,0 +1,22 @@
+variable "aws_region" {
+  description = "AWS region to deploy to"
+  type        = string
+  default     = "us-west-1"
+
+  validation {
+    condition     = can(regex("^[a-z]{2}(-gov)?-[a-z]+-[0-9]$", var.aws_region))
+    error_message = "aws_region must be a region name such as us-west-1."
+  }
+}
+
+variable "github_token" {
+  description = "GitHub Personal Access Token for repository access"
+  type        = string
+  sensitive   = true
+}
+
+variable "ssh_public_key_path" {
+  description = "Path to the public SSH key for instance access"
+  type        = string
+  default     = "~/.ssh/id_ed25519.pub"
+}
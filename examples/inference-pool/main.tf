@@ -0,0 +1,29 @@
+# OpenWebUI on a small instance in front of replica_count Ollama instances
+# that share their models through an S3 cache.
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.aws_region
+}
+
+module "ds_aws" {
+  source = "../.."
+
+  github_token        = var.github_token
+  ssh_public_key_path = var.ssh_public_key_path
+  instance_type       = "t3.large"
+
+  replica_count           = var.replica_count
+  inference_instance_type = "r6i.metal"
+  ollama_model            = "deepseek-r1:671b"
+
+  create_model_cache_bucket = true
+}
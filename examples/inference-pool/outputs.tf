@@ -0,0 +1,11 @@
+output "openwebui_url" {
+  value = module.ds_aws.openwebui_url
+}
+
+output "inference_ollama_url" {
+  value = module.ds_aws.inference_ollama_url
+}
+
+output "inference_instance_ids" {
+  value = module.ds_aws.inference_instance_ids
+}
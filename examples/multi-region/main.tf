@@ -0,0 +1,47 @@
+# The same deployment in two regions, each module instance using its own
+# provider configuration.
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  alias  = "west"
+  region = "us-west-1"
+}
+
+provider "aws" {
+  alias  = "east"
+  region = "us-east-1"
+}
+
+module "west" {
+  source = "../.."
+  providers = {
+    aws = aws.west
+  }
+
+  github_token        = var.github_token
+  ssh_public_key_path = var.ssh_public_key_path
+  ami_id              = var.ami_ids["us-west-1"]
+  instance_type       = "r6i.4xlarge"
+  ollama_model        = "deepseek-r1:14b"
+}
+
+module "east" {
+  source = "../.."
+  providers = {
+    aws = aws.east
+  }
+
+  github_token        = var.github_token
+  ssh_public_key_path = var.ssh_public_key_path
+  ami_id              = var.ami_ids["us-east-1"]
+  instance_type       = "r6i.4xlarge"
+  ollama_model        = "deepseek-r1:14b"
+}
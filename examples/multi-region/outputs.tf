@@ -0,0 +1,6 @@
+output "openwebui_urls" {
+  value = {
+    "us-west-1" = module.west.openwebui_url
+    "us-east-1" = module.east.openwebui_url
+  }
+}
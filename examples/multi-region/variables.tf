@@ -0,0 +1,16 @@
+variable "github_token" {
+  description = "GitHub Personal Access Token for repository access"
+  type        = string
+  sensitive   = true
+}
+
+variable "ssh_public_key_path" {
+  description = "Path to the public SSH key for instance access"
+  type        = string
+  default     = "~/.ssh/id_ed25519.pub"
+}
+
+variable "ami_ids" {
+  description = "Ubuntu 22.04 LTS AMI of each region, keyed by us-west-1 and us-east-1"
+  type        = map(string)
+}
@@ -1,4 +1,7 @@
 terraform {
+  # Variable validations reference locals, which needs 1.9
+  required_version = ">= 1.9.0"
+
   required_providers {
     aws = {
       source  = "hashicorp/aws"
@@ -8,216 +11,279 @@ terraform {
       source  = "hashicorp/random"
       version = "~> 3.0"
     }
+    tls = {
+      source  = "hashicorp/tls"
+      version = "~> 4.0"
+    }
+    local = {
+      source  = "hashicorp/local"
+      version = "~> 2.4"
+    }
   }
 }
 
-provider "aws" {
-  region = var.aws_region
-}
-
 # Generate unique ID for this deployment
 resource "random_id" "unique" {
   byte_length = 4
 }
 
+data "aws_region" "current" {}
+
 locals {
-  name_prefix = "ds-${random_id.unique.hex}"
-  user_data_vars = {
-    log_group_name    = aws_cloudwatch_log_group.app_logs.name
-    app_log_stream    = aws_cloudwatch_log_stream.app_log_stream.name
-    model_pull_stream = aws_cloudwatch_log_stream.model_pull_stream.name
-    github_token      = var.github_token
-  }
+  name_prefix = var.name_prefix != "" ? var.name_prefix : "ds-${random_id.unique.hex}"
+  region      = data.aws_region.current.name
+
+  # Applied to every taggable resource, under its own Name tag. The module has
+  # no provider of its own, so it cannot rely on the caller's default_tags
+  tags = merge(
+    {
+      Environment = var.environment
+      Deployment  = local.name_prefix
+      ManagedBy   = "terraform"
+    },
+    var.owner != "" ? { Owner = var.owner } : {},
+    var.cost_center != "" ? { CostCenter = var.cost_center } : {},
+    var.tags,
+  )
+
+  # Address users are given: the DNS name when configured, else the public IP.
+  # Empty in autoscaling mode without either, which leaves the URL and ssh
+  # outputs empty too; dsctl ssh and tunnel look up the group's instance
+  public_host = var.dns_hostname != "" ? var.dns_hostname : module.compute.public_ip
+
+  # Read from the version so instances never boot before the secret has a value
+  api_proxy_secret_arn = var.enable_api_proxy ? (
+    var.api_proxy_secret_arn != "" ? var.api_proxy_secret_arn : aws_secretsmanager_secret_version.api_proxy[0].arn
+  ) : ""
+
+  model_cache_enabled = var.create_model_cache_bucket || var.model_cache_bucket != ""
+  model_cache_bucket  = var.create_model_cache_bucket ? aws_s3_bucket.model_cache[0].bucket : var.model_cache_bucket
+
+  model_volume_enabled = var.model_volume_snapshot_id != "" || length(var.model_volume_snapshot_tags) > 0 || var.model_volume_size > 0
+
+  ssh_public_key = var.generate_ssh_key ? tls_private_key.ssh[0].public_key_openssh : (
+    var.ssh_public_key != "" ? var.ssh_public_key : (
+      var.ssh_public_key_path != "" ? file(var.ssh_public_key_path) : ""
+    )
+  )
+
+  # Identity file the ssh outputs pass to -i. A key kept in Secrets Manager is
+  # fetched into it first by ssh_key_fetch
+  ssh_key_in_secret = var.generate_ssh_key && var.ssh_private_key_storage == "secrets_manager"
+  ssh_private_key_path = (
+    var.ssh_private_key_path != "" ? var.ssh_private_key_path :
+    local.ssh_key_in_secret ? "${local.name_prefix}-ssh.pem" :
+    var.generate_ssh_key ? "${path.root}/${local.name_prefix}-ssh.pem" : ""
+  )
+  ssh_key_fetch = local.ssh_key_in_secret ? (
+    "(umask 077 && aws secretsmanager get-secret-value --region ${local.region} --secret-id ${aws_secretsmanager_secret.ssh_private_key[0].arn} --query SecretString --output text > ${local.ssh_private_key_path}) && "
+  ) : ""
+  ssh = local.public_host != "" ? "${local.ssh_key_fetch}ssh${local.ssh_private_key_path != "" ? " -i ${local.ssh_private_key_path}" : ""} ubuntu@${local.public_host}" : ""
 }
 
-resource "aws_vpc" "main" {
-  cidr_block           = "10.0.0.0/16"
-  enable_dns_hostnames = true
-  enable_dns_support   = true
+module "network" {
+  source = "./modules/network"
 
-  tags = {
-    Name = "${local.name_prefix}-vpc"
-  }
+  name_prefix       = local.name_prefix
+  tags              = local.tags
+  vpc_cidr_block    = var.vpc_cidr_block
+  availability_zone = var.availability_zone
+  secondary_subnet  = var.replica_count > 0 && var.inference_lb_type == "application"
 }
 
-resource "aws_cloudwatch_log_group" "app_logs" {
-  name              = "/${local.name_prefix}/logs"
-  retention_in_days = 30
+module "observability" {
+  source = "./modules/observability"
 
-  tags = {
-    Environment = "production"
-    Application = local.name_prefix
-  }
-}
+  name_prefix = local.name_prefix
+  tags        = local.tags
 
-resource "aws_cloudwatch_log_stream" "app_log_stream" {
-  name           = "${local.name_prefix}-stream"
-  log_group_name = aws_cloudwatch_log_group.app_logs.name
+  log_retention_days    = var.log_retention_days
+  notification_emails   = var.notification_emails
+  error_alarm_threshold = var.error_alarm_threshold
+  error_alarm_period    = var.error_alarm_period
 }
 
-resource "aws_cloudwatch_log_stream" "model_pull_stream" {
-  name           = "${local.name_prefix}-stream-model-pull"
-  log_group_name = aws_cloudwatch_log_group.app_logs.name
-}
+module "iam" {
+  source = "./modules/iam"
 
-resource "aws_subnet" "public" {
-  vpc_id                  = aws_vpc.main.id
-  cidr_block              = "10.0.1.0/24"
-  availability_zone       = "us-west-1b" 
-  map_public_ip_on_launch = true
+  name_prefix    = local.name_prefix
+  tags           = local.tags
+  log_group_arns = [module.observability.log_group_arn, module.observability.model_pull_log_group_arn]
 
-  tags = {
-    Name = "${local.name_prefix}-subnet"
-  }
-}
+  enable_api_proxy     = var.enable_api_proxy
+  api_proxy_secret_arn = local.api_proxy_secret_arn
 
-resource "aws_internet_gateway" "main" {
-  vpc_id = aws_vpc.main.id
+  enable_model_cache = local.model_cache_enabled
+  model_cache_bucket = local.model_cache_bucket
+  model_cache_prefix = var.model_cache_prefix
 
-  tags = {
-    Name = "${local.name_prefix}-igw"
-  }
+  enable_autoscaling     = var.enable_autoscaling
+  autoscaling_group_name = module.compute.autoscaling_group_name
+  claim_model_volume     = local.model_volume_enabled
+  model_volume_arn       = module.compute.model_volume_arn
+  claim_eip              = var.create_eip
+  eip_allocation_id      = module.compute.eip_allocation_id
 }
 
-resource "aws_route_table" "main" {
-  vpc_id = aws_vpc.main.id
+module "compute" {
+  source = "./modules/compute"
+
+  name_prefix      = local.name_prefix
+  tags             = local.tags
+  region           = local.region
+  ami_id           = var.ami_id
+  instance_type    = var.instance_type
+  root_device_name = var.root_device_name
+  ssh_public_key   = local.ssh_public_key
+
+  vpc_id                = module.network.vpc_id
+  vpc_cidr_block        = module.network.vpc_cidr_block
+  subnet_id             = module.network.public_subnet_id
+  ssh_cidr_blocks       = var.ssh_cidr_blocks
+  ingress_cidr_blocks   = var.ingress_cidr_blocks
+  availability_zone     = module.network.availability_zone
+  secondary_subnet_ids  = module.network.secondary_subnet_ids
+  instance_profile_name = module.iam.instance_profile_name
+
+  log_group_name            = module.observability.log_group_name
+  app_log_stream            = module.observability.app_log_stream
+  model_pull_log_group_name = module.observability.model_pull_log_group_name
+  model_pull_stream         = module.observability.model_pull_stream
+
+  github_token             = var.github_token
+  ollama_model             = var.ollama_model
+  ollama_keep_alive        = var.ollama_keep_alive
+  ollama_num_parallel      = var.ollama_num_parallel
+  ollama_max_loaded_models = var.ollama_max_loaded_models
+  ollama_context_length    = var.ollama_context_length
+  ollama_num_threads       = var.ollama_num_threads
+  ollama_warmup_models     = var.ollama_warmup_models
+
+  enable_api_proxy          = var.enable_api_proxy
+  api_proxy_port            = var.api_proxy_port
+  api_proxy_tls_mode        = var.api_proxy_tls_mode
+  api_proxy_domain          = var.api_proxy_domain
+  api_proxy_acme_email      = var.api_proxy_acme_email
+  api_proxy_auth_mode       = var.api_proxy_auth_mode
+  api_proxy_basic_auth_user = var.api_proxy_basic_auth_user
+  api_proxy_secret_arn      = local.api_proxy_secret_arn
+
+  model_cache_bucket = local.model_cache_bucket
+  model_cache_prefix = var.model_cache_prefix
+
+  model_volume_size                  = var.model_volume_size
+  model_volume_snapshot_id           = var.model_volume_snapshot_id
+  model_volume_snapshot_tags         = var.model_volume_snapshot_tags
+  model_volume_fast_snapshot_restore = var.model_volume_fast_snapshot_restore
+  model_volume_throughput            = var.model_volume_throughput
+
+  enable_autoscaling            = var.enable_autoscaling
+  asg_min_size                  = var.asg_min_size
+  asg_max_size                  = var.asg_max_size
+  asg_health_check_type         = var.asg_health_check_type
+  asg_health_check_grace_period = var.asg_health_check_grace_period
+  create_eip                    = var.create_eip
+
+  replica_count             = var.replica_count
+  inference_instance_type   = var.inference_instance_type
+  inference_lb_type         = var.inference_lb_type
+  inference_sticky_sessions = var.inference_sticky_sessions
+
+  route53_zone_id = var.route53_zone_id
+  dns_hostname    = var.dns_hostname
+}
 
-  route {
-    cidr_block = "0.0.0.0/0"
-    gateway_id = aws_internet_gateway.main.id
-  }
+# The proxy credential and the model cache bucket are shared by the iam and
+# compute modules and outlive any single instance
+resource "random_password" "api_proxy" {
+  count = var.enable_api_proxy && var.api_proxy_secret_arn == "" ? 1 : 0
 
-  tags = {
-    Name = "${local.name_prefix}-rt"
-  }
+  length  = 48
+  special = false
 }
 
-resource "aws_route_table_association" "main" {
-  subnet_id      = aws_subnet.public.id
-  route_table_id = aws_route_table.main.id
-}
+resource "aws_secretsmanager_secret" "api_proxy" {
+  count = var.enable_api_proxy && var.api_proxy_secret_arn == "" ? 1 : 0
 
-resource "aws_iam_role" "ec2_cloudwatch" {
-  name = "${local.name_prefix}-role"
+  name                    = "${local.name_prefix}-ollama-api-token"
+  description             = "Credential enforced by the Ollama API proxy for ${local.name_prefix}"
+  recovery_window_in_days = 0
 
-  assume_role_policy = jsonencode({
-    Version = "2012-10-17"
-    Statement = [
-      {
-        Action = "sts:AssumeRole"
-        Effect = "Allow"
-        Principal = {
-          Service = "ec2.amazonaws.com"
-        }
-      }
-    ]
+  tags = merge(local.tags, {
+    Name = "${local.name_prefix}-ollama-api-token"
   })
 }
 
-resource "aws_iam_role_policy" "cloudwatch_policy" {
-  name = "${local.name_prefix}-policy"
-  role = aws_iam_role.ec2_cloudwatch.id
-
-  policy = jsonencode({
-    Version = "2012-10-17"
-    Statement = [
-      {
-        Effect = "Allow"
-        Action = [
-          "logs:CreateLogGroup",
-          "logs:CreateLogStream",
-          "logs:PutLogEvents",
-          "logs:DescribeLogStreams"
-        ]
-        Resource = [
-          "${aws_cloudwatch_log_group.app_logs.arn}",
-          "${aws_cloudwatch_log_group.app_logs.arn}:*"
-        ]
-      }
-    ]
-  })
+resource "aws_secretsmanager_secret_version" "api_proxy" {
+  count = var.enable_api_proxy && var.api_proxy_secret_arn == "" ? 1 : 0
+
+  secret_id     = aws_secretsmanager_secret.api_proxy[0].id
+  secret_string = random_password.api_proxy[0].result
 }
 
-resource "aws_iam_instance_profile" "ec2_profile" {
-  name = "${local.name_prefix}-profile"
-  role = aws_iam_role.ec2_cloudwatch.name
+resource "aws_s3_bucket" "model_cache" {
+  count = var.create_model_cache_bucket ? 1 : 0
+
+  bucket        = "${local.name_prefix}-model-cache"
+  force_destroy = var.model_cache_force_destroy
+
+  tags = merge(local.tags, {
+    Name = "${local.name_prefix}-model-cache"
+  })
 }
 
-resource "aws_security_group" "app" {
-  name        = "${local.name_prefix}-sg"
-  description = "Security group for ${local.name_prefix}"
-  vpc_id      = aws_vpc.main.id
+resource "aws_s3_bucket_public_access_block" "model_cache" {
+  count = var.create_model_cache_bucket ? 1 : 0
 
-  ingress {
-    from_port   = 22
-    to_port     = 22
-    protocol    = "tcp"
-    cidr_blocks = ["0.0.0.0/0"]
-    description = "SSH"
-  }
+  bucket                  = aws_s3_bucket.model_cache[0].id
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
 
-  ingress {
-    from_port   = 8080
-    to_port     = 8080
-    protocol    = "tcp"
-    cidr_blocks = ["0.0.0.0/0"]
-    description = "OpenWebUI"
-  }
+resource "aws_s3_bucket_server_side_encryption_configuration" "model_cache" {
+  count = var.create_model_cache_bucket ? 1 : 0
 
-  ingress {
-    from_port   = 11434
-    to_port     = 11434
-    protocol    = "tcp"
-    cidr_blocks = ["0.0.0.0/0"]
-    description = "Ollama"
-  }
+  bucket = aws_s3_bucket.model_cache[0].id
 
-  egress {
-    from_port   = 0
-    to_port     = 0
-    protocol    = "-1"
-    cidr_blocks = ["0.0.0.0/0"]
+  rule {
+    apply_server_side_encryption_by_default {
+      sse_algorithm = "AES256"
+    }
   }
+}
 
-  tags = {
-    Name = "${local.name_prefix}-sg"
-  }
+# Generated key pair for deployments without a key of their own
+resource "tls_private_key" "ssh" {
+  count     = var.generate_ssh_key ? 1 : 0
+  algorithm = "ED25519"
 }
 
-resource "aws_key_pair" "app" {
-  key_name   = "${local.name_prefix}-key"
-  public_key = file(var.ssh_public_key_path)
+resource "local_sensitive_file" "ssh_private_key" {
+  count = var.generate_ssh_key && var.ssh_private_key_storage == "local_file" ? 1 : 0
+
+  filename             = pathexpand(local.ssh_private_key_path)
+  content              = tls_private_key.ssh[0].private_key_openssh
+  file_permission      = "0600"
+  directory_permission = "0700"
 }
 
-resource "aws_instance" "app" {
-  ami           = var.ami_id
-  instance_type = var.instance_type
-  subnet_id                   = aws_subnet.public.id
-  vpc_security_group_ids      = [aws_security_group.app.id]
-  associate_public_ip_address = true
-  iam_instance_profile        = aws_iam_instance_profile.ec2_profile.name
-  key_name                    = aws_key_pair.app.key_name
+resource "aws_secretsmanager_secret" "ssh_private_key" {
+  count = local.ssh_key_in_secret ? 1 : 0
 
-  root_block_device {
-    volume_size = 1000
-    volume_type = "gp3"
-    tags = {
-      Name = "${local.name_prefix}-volume"
-    }
-  }
+  name                    = "${local.name_prefix}-ssh-private-key"
+  description             = "Private SSH key of the ubuntu user on ${local.name_prefix} instances"
+  recovery_window_in_days = 0
 
-  tags = {
-    Name        = "${local.name_prefix}-instance"
-    Purpose     = "ollama-inference"
-    Environment = "production"
-    ManagedBy   = "terraform"
-  }
+  tags = merge(local.tags, {
+    Name = "${local.name_prefix}-ssh-private-key"
+  })
+}
 
-  user_data = templatefile("${path.module}/templates/user_data.sh", local.user_data_vars)
+resource "aws_secretsmanager_secret_version" "ssh_private_key" {
+  count = local.ssh_key_in_secret ? 1 : 0
 
-  depends_on = [
-    aws_internet_gateway.main,
-    aws_cloudwatch_log_stream.model_pull_stream,
-    aws_cloudwatch_log_stream.app_log_stream
-  ]
+  secret_id     = aws_secretsmanager_secret.ssh_private_key[0].id
+  secret_string = tls_private_key.ssh[0].private_key_openssh
 }
@@ -0,0 +1,55 @@
+terraform {
+  required_providers {
+    cloudinit = {
+      source  = "hashicorp/cloudinit"
+      version = "~> 2.3"
+    }
+  }
+}
+
+locals {
+  # Run in this order by run.sh; models continues in the background
+  stage_names = concat(
+    ["cloudwatch", "packages", "docker"],
+    var.template_vars.enable_autoscaling ? ["claim"] : [],
+    ["app"],
+    var.template_vars.node_role != "web" ? ["models"] : [],
+  )
+
+  stages = { for name in local.stage_names : name => templatefile("${path.module}/templates/${name}.sh", var.template_vars) }
+
+  # The runner is installed next to the stages so that it can be rerun
+  helpers = {
+    lib = templatefile("${path.module}/templates/lib.sh", var.template_vars)
+    run = templatefile("${path.module}/templates/run.sh", var.template_vars)
+  }
+}
+
+# cloud-init writes the runner, helpers and stages to disk, then starts the
+# runner once.
+# Compressed, the multipart stays well below the 16 KB user data limit.
+data "cloudinit_config" "this" {
+  gzip          = true
+  base64_encode = true
+
+  part {
+    filename     = "bootstrap-files.yaml"
+    content_type = "text/cloud-config"
+    content = join("\n", ["#cloud-config", yamlencode({
+      write_files = [
+        for name, content in merge(local.stages, local.helpers) : {
+          path        = "/opt/ds-aws/bootstrap/${name}.sh"
+          owner       = "root:root"
+          permissions = "0700"
+          content     = content
+        }
+      ]
+    })])
+  }
+
+  part {
+    filename     = "start.sh"
+    content_type = "text/x-shellscript"
+    content      = "#!/bin/bash\nexec /opt/ds-aws/bootstrap/run.sh\n"
+  }
+}
@@ -0,0 +1,16 @@
+output "user_data" {
+  description = "Gzipped, base64-encoded cloud-init multipart that installs and runs the bootstrap stages"
+  value       = data.cloudinit_config.this.rendered
+  sensitive   = true
+}
+
+output "stages" {
+  description = "Rendered stage scripts by name"
+  value       = local.stages
+  sensitive   = true
+}
+
+output "stage_names" {
+  description = "Stages in the order the runner runs them"
+  value       = local.stage_names
+}
@@ -0,0 +1,46 @@
+#!/bin/bash
+# Stage claim (autoscaling only): the Auto Scaling group holds this instance in
+# Pending:Wait until it has claimed the deployment's persistent resources from
+# its predecessor
+# shellcheck disable=SC1091
+set -e
+source /opt/ds-aws/bootstrap/lib.sh
+
+INSTANCE_ID=$(imds instance-id)
+
+complete_launch_hook() {
+    log "Completing launch lifecycle hook with $1"
+    aws_cli autoscaling complete-lifecycle-action \
+        --auto-scaling-group-name "${autoscaling_group_name}" \
+        --lifecycle-hook-name "${lifecycle_hook_name}" \
+        --instance-id "$INSTANCE_ID" \
+        --lifecycle-action-result "$1"
+}
+%{ if model_volume_id != "" ~}
+
+# The volume stays attached to a terminating predecessor for a while. Nitro
+# instances expose it as an NVMe device whose serial is the volume ID, so a
+# rerun finds it already attached
+MODEL_VOLUME_SERIAL=$(echo "${model_volume_id}" | tr -d '-')
+if [ -b "/dev/disk/by-id/nvme-Amazon_Elastic_Block_Store_$MODEL_VOLUME_SERIAL" ] || [ -b /dev/xvdf ]; then
+    log "Model volume ${model_volume_id} is already attached"
+else
+    log "Attaching model volume ${model_volume_id}"
+    if ! retry "Model volume attachment" 60 15 aws_cli ec2 attach-volume --volume-id "${model_volume_id}" --instance-id "$INSTANCE_ID" --device /dev/sdf >/dev/null; then
+        log_error "Could not attach model volume ${model_volume_id}"
+        complete_launch_hook ABANDON
+        exit 1
+    fi
+fi
+%{ endif ~}
+%{ if eip_allocation_id != "" ~}
+
+log "Associating Elastic IP ${eip_allocation_id}"
+if ! aws_cli ec2 associate-address --allocation-id "${eip_allocation_id}" --instance-id "$INSTANCE_ID" --allow-reassociation >/dev/null; then
+    log_error "Could not associate Elastic IP ${eip_allocation_id}"
+    complete_launch_hook ABANDON
+    exit 1
+fi
+%{ endif ~}
+
+complete_launch_hook CONTINUE
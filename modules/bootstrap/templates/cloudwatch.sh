@@ -0,0 +1,47 @@
+#!/bin/bash
+# Stage cloudwatch: install the CloudWatch agent and ship the deployment logs
+# shellcheck disable=SC1091
+set -e
+source /opt/ds-aws/bootstrap/lib.sh
+
+log "Installing CloudWatch agent"
+wget -q -O /tmp/amazon-cloudwatch-agent.deb https://s3.amazonaws.com/amazoncloudwatch-agent/ubuntu/amd64/latest/amazon-cloudwatch-agent.deb
+dpkg -i /tmp/amazon-cloudwatch-agent.deb
+
+log "Configuring CloudWatch agent"
+mkdir -p /opt/aws/amazon-cloudwatch-agent/bin/
+cat > /opt/aws/amazon-cloudwatch-agent/bin/config.json << 'CWAGENTCONFIG'
+{
+  "agent": {
+    "run_as_user": "root"
+  },
+  "logs": {
+    "metrics_collected": {
+      "emf": {}
+    },
+    "logs_collected": {
+      "files": {
+        "collect_list": [
+          {
+            "file_path": "/var/log/deploy.log",
+            "log_group_name": "${log_group_name}",
+            "log_stream_name": "${app_log_stream}",
+            "timestamp_format": "%Y-%m-%dT%H:%M:%S.%fZ",
+            "timezone": "UTC"
+          },
+          {
+            "file_path": "/var/log/model-pull.log",
+            "log_group_name": "${model_pull_log_group_name}",
+            "log_stream_name": "${model_pull_stream}",
+            "timestamp_format": "%Y-%m-%dT%H:%M:%S.%fZ",
+            "timezone": "UTC"
+          }
+        ]
+      }
+    }
+  }
+}
+CWAGENTCONFIG
+
+/opt/aws/amazon-cloudwatch-agent/bin/amazon-cloudwatch-agent-ctl -a fetch-config -m ec2 -s -c file:/opt/aws/amazon-cloudwatch-agent/bin/config.json
+systemctl start amazon-cloudwatch-agent
@@ -0,0 +1,53 @@
+#!/bin/bash
+# Stage docker: Docker Engine and the compose plugin
+# shellcheck disable=SC1091
+set -e
+source /opt/ds-aws/bootstrap/lib.sh
+
+NUM_CORES=$(nproc)
+
+log "Installing Docker"
+install -m 0755 -d /etc/apt/keyrings
+curl -fsSL https://download.docker.com/linux/ubuntu/gpg -o /etc/apt/keyrings/docker.asc
+chmod a+r /etc/apt/keyrings/docker.asc
+
+echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.asc] https://download.docker.com/linux/ubuntu \
+    $(. /etc/os-release && echo "$VERSION_CODENAME") stable" | \
+    tee /etc/apt/sources.list.d/docker.list > /dev/null
+
+DEBIAN_FRONTEND=noninteractive apt-get update
+DEBIAN_FRONTEND=noninteractive apt-get install -y \
+    --no-install-recommends \
+    -o Dpkg::Options::="--force-confdef" \
+    -o Dpkg::Options::="--force-confold" \
+    -o DPkg::MaxProcs="$NUM_CORES" \
+    docker-ce \
+    docker-ce-cli \
+    containerd.io \
+    docker-compose-plugin
+
+log "Configuring Docker daemon"
+mkdir -p /etc/docker
+cat > /etc/docker/daemon.json << 'DOCKERCONFIG'
+{
+    "log-driver": "json-file",
+    "log-opts": {
+        "max-size": "10m",
+        "max-file": "3"
+    },
+    "storage-driver": "overlay2",
+    "metrics-addr" : "0.0.0.0:9323",
+    "experimental" : true
+}
+DOCKERCONFIG
+
+usermod -aG docker ubuntu
+
+systemctl daemon-reload
+systemctl restart docker
+
+log "Waiting for Docker to be ready"
+timeout 60 bash -c 'until docker info >/dev/null 2>&1; do sleep 1; done'
+
+# Ensure docker socket has correct permissions
+chmod 666 /var/run/docker.sock
@@ -0,0 +1,123 @@
+#!/bin/bash
+# Helpers shared by the bootstrap runner and every stage. Stages are separate
+# scripts so that a failed stage can be retried on its own; each one must be
+# safe to run again after a partial failure.
+
+BOOTSTRAP_DIR=/opt/ds-aws/bootstrap
+STAGE_DIR=/var/lib/ds-aws/stages
+
+# Log lines are JSON objects so that CloudWatch Logs Insights can query their
+# fields. The stage and attempt come from LOG_STAGE and LOG_ATTEMPT, which
+# run_stage and retry set for everything they run.
+
+# json_string TEXT prints TEXT as a JSON string. Control characters other than
+# tab, CR and LF, such as the escape codes of progress bars, are dropped.
+json_string() {
+    local s=$1
+    s=$${s//[$'\001'-$'\010'$'\013'$'\014'$'\016'-$'\037']/}
+    s=$${s//\\/\\\\}
+    s=$${s//\"/\\\"}
+    s=$${s//$'\t'/\\t}
+    s=$${s//$'\r'/\\r}
+    s=$${s//$'\n'/\\n}
+    printf '"%s"' "$s"
+}
+
+now_ms() {
+    date +%s%3N
+}
+
+# log_event LEVEL MESSAGE [DURATION_MS]
+log_event() {
+    printf '{"timestamp":"%s","deployment_id":"%s","stage":"%s","level":"%s","attempt":%s,"duration_ms":%s,"message":%s}\n' \
+        "$(date -u '+%Y-%m-%dT%H:%M:%S.%3NZ')" "${deployment_id}" "$${LOG_STAGE:-runner}" "$1" \
+        "$${LOG_ATTEMPT:-null}" "$${3:-null}" "$(json_string "$2")"
+}
+
+log() {
+    log_event info "$*"
+}
+
+log_warn() {
+    log_event warn "$*"
+}
+
+log_error() {
+    log_event error "$*"
+}
+
+# log_output turns the plain lines commands print into log events and passes
+# log events through unchanged
+log_output() {
+    local line
+    while IFS= read -r line; do
+        case "$line" in
+            "{"*"}") printf '%s\n' "$line" ;;
+            *) log "$line" ;;
+        esac
+    done
+}
+
+# retry LABEL ATTEMPTS DELAY COMMAND...
+# Runs COMMAND until it succeeds, sleeping DELAY seconds between attempts
+retry() {
+    local label=$1 attempts=$2 delay=$3 attempt=1 start attempt_start
+    local -x LOG_ATTEMPT
+    shift 3
+    start=$(now_ms)
+    while true; do
+        LOG_ATTEMPT=$attempt
+        log "$label attempt $attempt of $attempts"
+        attempt_start=$(now_ms)
+        if "$@"; then
+            return 0
+        fi
+        log_event error "$label attempt $attempt failed" $(($(now_ms) - attempt_start))
+        if [ "$attempt" -ge "$attempts" ]; then
+            log_event error "$label failed after $attempts attempts" $(($(now_ms) - start))
+            return 1
+        fi
+        sleep "$delay"
+        attempt=$((attempt + 1))
+    done
+}
+
+# Stages that retry internally, or must not be repeated, run once
+declare -A STAGE_ATTEMPTS=([claim]=1 [models]=1)
+
+# stage_script NAME runs $BOOTSTRAP_DIR/NAME.sh, logging its output
+stage_script() {
+    bash "$BOOTSTRAP_DIR/$1.sh" 2>&1 | log_output
+    return "$${PIPESTATUS[0]}"
+}
+
+# run_stage NAME
+# Runs $BOOTSTRAP_DIR/NAME.sh unless its completion marker exists, and writes
+# the marker once the stage succeeds
+run_stage() {
+    local name=$1 attempts=$${STAGE_ATTEMPTS[$1]:-3} start
+    local -x LOG_STAGE=$1
+    if [ -f "$STAGE_DIR/$name.done" ]; then
+        log "Stage $name already completed, skipping"
+        return 0
+    fi
+    mkdir -p "$STAGE_DIR"
+    start=$(now_ms)
+    if ! retry "Stage $name" "$attempts" 30 stage_script "$name"; then
+        return 1
+    fi
+    date '+%Y-%m-%d %H:%M:%S' > "$STAGE_DIR/$name.done"
+    log_event info "Stage $name completed" $(($(now_ms) - start))
+}
+
+# imds PATH reads instance metadata through IMDSv2
+imds() {
+    local token
+    token=$(curl -s -X PUT "http://169.254.169.254/latest/api/token" -H "X-aws-ec2-metadata-token-ttl-seconds: 300")
+    curl -s -H "X-aws-ec2-metadata-token: $token" "http://169.254.169.254/latest/meta-data/$1"
+}
+
+# The AWS CLI is not installed on the host; it runs from its image
+aws_cli() {
+    docker run --rm --network host amazon/aws-cli --region "${aws_region}" "$@"
+}
@@ -0,0 +1,365 @@
+#!/bin/bash
+# Stage models: pull, verify and warm up the models, then probe that Ollama
+# serves them. Runs in the background and logs to model-pull.log. Pulls resume
+# from the blobs already on disk, so a rerun only fetches what is missing.
+# shellcheck disable=SC1091
+source /opt/ds-aws/bootstrap/lib.sh
+
+LOG_FILE="/var/log/model-pull.log"
+
+# Capture all output as log events
+exec > >(log_output | tee -a "$LOG_FILE") 2>&1
+
+log "Starting model stage with PID $$"
+
+MODEL="${ollama_model}"
+OLLAMA_URL="http://localhost:11434"
+PULL_STREAM="${model_pull_stream}"
+%{ if node_role == "inference" ~}
+
+# The agent expands {instance_id} in its own configuration, but EMF records
+# name their stream explicitly
+PULL_STREAM=$(echo "$PULL_STREAM" | sed "s/{instance_id}/$(imds instance-id)/")
+%{ endif ~}
+PULL_SOURCE=registry
+PULL_SECONDS=0
+WARMED_MODELS=""
+%{ if ollama_num_threads > 0 ~}
+NUM_THREADS=${ollama_num_threads}
+%{ else ~}
+NUM_THREADS=$(nproc)
+%{ endif ~}
+
+# Record the outcome as a single log event with the result fields added, so
+# it can be queried in CloudWatch
+report_status() {
+    local status=$1 reason=$2 level=info message="Model $MODEL is ready"
+    if [ "$status" != "ready" ]; then
+        level=error
+        message="Model $MODEL $status: $reason"
+    fi
+    log_event "$level" "$message" | jq -c \
+        --arg status "$status" \
+        --arg reason "$reason" \
+        --arg model "$MODEL" \
+        --arg source "$PULL_SOURCE" \
+        --arg digest "$MODEL_DIGEST" \
+        --arg version "$OLLAMA_VERSION" \
+        --argjson layers "$LAYERS_VERIFIED" \
+        --argjson size "$MODEL_SIZE" \
+        --argjson pull_seconds "$PULL_SECONDS" \
+        --argjson probe_ms "$PROBE_MS" \
+        --argjson num_threads "$NUM_THREADS" \
+        --arg warmed "$WARMED_MODELS" \
+        '. + {event: "model_pull_result", status: $status, model: $model,
+          source: $source, ollama_version: $version, digest: $digest, size_bytes: $size,
+          layers_verified: $layers, pull_seconds: $pull_seconds, probe_ms: $probe_ms,
+          num_threads: $num_threads, warmed_models: ($warmed | split(" ") | map(select(. != "")))}
+         + (if $reason == "" then {} else {reason: $reason} end)'
+}
+MODEL_DIGEST=""
+OLLAMA_VERSION=""
+LAYERS_VERIFIED=0
+MODEL_SIZE=0
+PROBE_MS=0
+
+fail() {
+    log_error "$1"
+    report_status failed "$1"
+    exit 1
+}
+
+# Pull progress is published as CloudWatch embedded metric format through the
+# agent's EMF listener, so no extra IAM permissions are needed
+emit_pull_metrics() {
+    local percent=$1 bytes_per_second=$2
+    jq -cn \
+        --arg group "${model_pull_log_group_name}" \
+        --arg stream "$PULL_STREAM" \
+        --arg deployment "${deployment_id}" \
+        --arg model "$MODEL" \
+        --argjson percent "$percent" \
+        --argjson rate "$bytes_per_second" \
+        '{_aws: {Timestamp: (now * 1000 | floor), LogGroupName: $group, LogStreamName: $stream,
+          CloudWatchMetrics: [{Namespace: "DsAws/ModelPull", Dimensions: [["Deployment", "Model"]],
+            Metrics: [{Name: "PullPercent", Unit: "Percent"}, {Name: "PullBytesPerSecond", Unit: "Bytes/Second"}]}]},
+          Deployment: $deployment, Model: $model, PullPercent: $percent, PullBytesPerSecond: $rate}' \
+        2>/dev/null > /dev/tcp/127.0.0.1/25888 || true
+}
+
+# Stream /api/pull, tracking bytes across layers. Ollama announces layers as
+# it reaches them, so the percentage covers the layers seen so far. Returns
+# non-zero when the stream reports an error or ends without success.
+pull_with_progress() {
+    local status digest total completed error
+    local pulled=0 expected=0 last_pulled=0 last_emit
+    local -A layer_total=() layer_completed=()
+    local result=1
+    last_emit=$(date +%s)
+
+    # Fields are separated by ASCII unit separators: bash merges adjacent
+    # whitespace separators such as tabs, which would shift empty fields
+    while IFS=$'\x1f' read -r status digest total completed error; do
+        if [ -n "$error" ]; then
+            log_error "Pull error: $error"
+            return 1
+        fi
+        if [ -n "$digest" ] && [ "$total" -gt 0 ]; then
+            if [ -z "$${layer_total[$digest]}" ]; then
+                layer_total[$digest]=$total
+                layer_completed[$digest]=0
+                expected=$((expected + total))
+            fi
+            pulled=$((pulled + completed - layer_completed[$digest]))
+            layer_completed[$digest]=$completed
+        fi
+
+        local now
+        now=$(date +%s)
+        if [ $((now - last_emit)) -ge 10 ] && [ "$expected" -gt 0 ]; then
+            local percent rate
+            percent=$(awk -v p="$pulled" -v e="$expected" 'BEGIN { printf "%.2f", 100 * p / e }')
+            rate=$(( (pulled - last_pulled) / (now - last_emit) ))
+            log "$status: $percent% ($((rate / 1048576)) MiB/s)"
+            emit_pull_metrics "$percent" "$rate"
+            last_emit=$now
+            last_pulled=$pulled
+        fi
+
+        if [ "$status" = "success" ]; then
+            result=0
+        fi
+    done < <(curl -sS -N --fail-with-body "$OLLAMA_URL/api/pull" \
+        -d "$(jq -cn --arg model "$MODEL" '{model: $model, stream: true}')" |
+        jq --unbuffered -r '[.status // "", .digest // "", .total // 0, .completed // 0, .error // ""] | map(tostring) | join("\u001f")')
+
+    if [ "$result" -eq 0 ]; then
+        emit_pull_metrics 100 0
+    fi
+    return "$result"
+}
+
+# Manifests live under registry/namespace/model/tag; official models use the
+# "library" namespace on registry.ollama.ai
+manifest_path() {
+    local name=$1 tag=latest
+    case "$name" in
+        *:*) tag=$(echo "$name" | sed 's/.*://'); name=$(echo "$name" | sed 's/:[^:]*$//') ;;
+    esac
+    case "$name" in
+        */*/*) ;;
+        */*) name="registry.ollama.ai/$name" ;;
+        *) name="registry.ollama.ai/library/$name" ;;
+    esac
+    echo "$MODELS_DIR/manifests/$name/$tag"
+}
+
+# Check every layer in the manifest against its sha256 digest and make sure
+# Ollama can load the model metadata
+verify_model() {
+    local manifest digest blob
+    if ! curl -sf "$OLLAMA_URL/api/show" -d "$(jq -cn --arg model "$MODEL" '{model: $model}')" | jq -e '.details' >/dev/null; then
+        log "/api/show did not return model details"
+        return 1
+    fi
+
+    manifest=$(manifest_path "$MODEL")
+    if [ ! -f "$manifest" ]; then
+        log "Manifest $manifest is missing"
+        return 1
+    fi
+    MODEL_DIGEST="sha256:$(sha256sum "$manifest" | cut -d' ' -f1)"
+    MODEL_SIZE=$(jq '[.config.size, .layers[].size] | add' "$manifest")
+    LAYERS_VERIFIED=0
+
+    for digest in $(jq -r '.config.digest, .layers[].digest' "$manifest"); do
+        blob="$MODELS_DIR/blobs/$(echo "$digest" | tr ':' '-')"
+        if [ ! -f "$blob" ]; then
+            log "Blob $digest is missing"
+            return 1
+        fi
+        if [ "sha256:$(sha256sum "$blob" | cut -d' ' -f1)" != "$digest" ]; then
+            log "Blob $digest does not match its digest"
+            return 1
+        fi
+        LAYERS_VERIFIED=$((LAYERS_VERIFIED + 1))
+    done
+    log "Verified $LAYERS_VERIFIED layers of $MODEL ($MODEL_DIGEST)"
+}
+
+# Ollama has no server-wide thread setting, so num_thread is stored as a
+# default parameter of the model. Requests that do not override it then reuse
+# the loaded runner instead of reloading the weights.
+set_num_threads() {
+    local model=$1
+    curl -sf "$OLLAMA_URL/api/create" \
+        -d "$(jq -cn --arg model "$model" --argjson threads "$NUM_THREADS" \
+            '{model: $model, from: $model, parameters: {num_thread: $threads}, stream: false}')" >/dev/null
+}
+
+# A generate request without a prompt only loads the model into memory, where
+# it stays for OLLAMA_KEEP_ALIVE
+warm_up() {
+    local model=$1 start
+    start=$(date +%s)
+    curl -sf --max-time 7200 "$OLLAMA_URL/api/generate" \
+        -d "$(jq -cn --arg model "$model" '{model: $model}')" | jq -e '.done == true' >/dev/null || return 1
+    log "Loaded $model into memory in $(($(date +%s) - start))s"
+}
+
+# Generate a single token to prove the model loads and serves requests
+probe_model() {
+    local start response
+    start=$(date +%s%3N)
+    response=$(curl -sf --max-time 3600 "$OLLAMA_URL/api/generate" \
+        -d "$(jq -cn --arg model "$MODEL" '{model: $model, prompt: "ping", stream: false, options: {num_predict: 1}}')") || return 1
+    PROBE_MS=$(($(date +%s%3N) - start))
+    echo "$response" | jq -e '.done == true' >/dev/null
+}
+
+# Function to check if docker container is running and healthy
+check_container() {
+    local container_name=$1
+    local max_attempts=$2
+    local attempt=1
+    
+    log "Checking status of container: $container_name"
+    
+    while [ $attempt -le $max_attempts ]; do
+        # Check if container exists and is running
+        if docker container inspect "$container_name" --format '{{.State.Running}}' 2>/dev/null | grep -q "true"; then
+            log "Container $container_name is running, checking API..."
+            
+            # Test Ollama API health
+            if curl -s -f "http://localhost:11434/api/tags" >/dev/null 2>&1; then
+                log "Ollama API is responding"
+                return 0
+            else
+                log "Waiting for Ollama API to be ready..."
+            fi
+        else
+            log "Attempt $attempt/$max_attempts: Container $container_name not ready yet"
+        fi
+        
+        sleep 30
+        ((attempt++))
+    done
+    
+    log_error "Container $container_name failed to start after $max_attempts attempts"
+    return 1
+}
+
+# Wait for Ollama container to be ready
+if ! check_container "ollama" 20; then
+    fail "Ollama container not ready"
+fi
+OLLAMA_VERSION=$(curl -sf "$OLLAMA_URL/api/version" | jq -r '.version // ""')
+
+OLLAMA_HOME=$(docker inspect ollama --format '{{range .Mounts}}{{if eq .Destination "/root/.ollama"}}{{.Source}}{{end}}{{end}}')
+MODELS_DIR="$OLLAMA_HOME/models"
+
+MODEL_CACHED=false
+%{ if model_cache_bucket != "" ~}
+
+# Restore Ollama blobs and manifests from the S3 model cache
+MODEL_CACHE_URI="s3://${model_cache_bucket}/${model_cache_prefix}"
+
+mkdir -p /root/.aws "$MODELS_DIR"
+cat > /root/.aws/config << 'AWSCONFIG'
+[default]
+s3 =
+    max_concurrent_requests = 64
+    multipart_chunksize = 64MB
+AWSCONFIG
+
+aws_s3() {
+    docker run --rm --network host \
+        -v /root/.aws:/root/.aws:ro \
+        -v "$MODELS_DIR:/models" \
+        amazon/aws-cli --region "${aws_region}" s3 "$@"
+}
+
+# Blobs are named after their sha256 digest; drop any that do not match so
+# the registry pull fetches them again
+verify_blobs() {
+    find "$MODELS_DIR/blobs" -type f -name 'sha256-*' -print0 2>/dev/null | \
+        xargs -0 -r -P "$(nproc)" -n 1 sh -c '
+            want=$(basename "$1" | sed "s/^sha256-//")
+            got=$(sha256sum "$1" | cut -d" " -f1)
+            if [ "$want" != "$got" ]; then
+                echo "Removing corrupt blob $1"
+                rm -f "$1"
+            fi' _
+}
+
+log "Restoring models from $MODEL_CACHE_URI"
+if aws_s3 sync --only-show-errors "$MODEL_CACHE_URI/" /models/; then
+    verify_blobs
+    # ollama show only reads the manifest and config, so a model whose weights
+    # were removed above still shows; only a verified model skips the pull
+    if docker exec ollama ollama show "${ollama_model}" >/dev/null 2>&1; then
+        if verify_model; then
+            log "Restored ${ollama_model} from the model cache, skipping registry pull"
+            MODEL_CACHED=true
+            PULL_SOURCE=cache
+        else
+            log_warn "Cached ${ollama_model} failed verification, falling back to the registry"
+        fi
+    fi
+else
+    log_warn "Model cache restore failed, falling back to the registry"
+fi
+%{ endif ~}
+
+# Pull the model with retries
+MAX_PULL_ATTEMPTS=3
+pull_start=$(date +%s)
+
+if [ "$MODEL_CACHED" = false ]; then
+    log "Pulling model: ${ollama_model}"
+    if ! retry "Model pull" $MAX_PULL_ATTEMPTS 60 pull_with_progress; then
+        PULL_SECONDS=$(($(date +%s) - pull_start))
+        fail "Failed to pull model after $MAX_PULL_ATTEMPTS attempts"
+    fi
+    log "Successfully pulled model: ${ollama_model}"
+fi
+PULL_SECONDS=$(($(date +%s) - pull_start))
+
+# A restored model was verified before skipping the pull
+if [ "$MODEL_CACHED" = false ] && ! verify_model; then
+    fail "Integrity verification of ${ollama_model} failed"
+fi
+%{ if model_cache_bucket != "" ~}
+
+# Upload newly pulled blobs and manifests so the next instance can skip the registry
+if [ "$MODEL_CACHED" = false ]; then
+    log "Saving models to $MODEL_CACHE_URI"
+    if ! aws_s3 sync --only-show-errors /models/ "$MODEL_CACHE_URI/"; then
+        log_warn "Failed to update the model cache"
+    fi
+fi
+%{ endif ~}
+
+for model in "$MODEL"%{ for m in ollama_warmup_models } "${m}"%{ endfor }; do
+    if [ "$model" != "$MODEL" ] && ! curl -sf "$OLLAMA_URL/api/show" -d "$(jq -cn --arg model "$model" '{model: $model}')" >/dev/null; then
+        log "Pulling warm-up model $model"
+        docker exec ollama ollama pull "$model" || fail "Failed to pull warm-up model $model"
+    fi
+    log "Setting num_thread=$NUM_THREADS for $model"
+    set_num_threads "$model" || fail "Failed to set num_thread for $model"
+done
+%{ for m in ollama_warmup_models ~}
+
+log "Warming up ${m}"
+warm_up "${m}" || fail "Warm-up of ${m} failed"
+WARMED_MODELS="$WARMED_MODELS ${m}"
+%{ endfor ~}
+
+log "Running readiness probe"
+if ! probe_model; then
+    fail "Readiness probe for ${ollama_model} failed"
+fi
+
+report_status ready ""
+log "Model stage completed successfully"
+log "Deployment completed"
@@ -0,0 +1,40 @@
+#!/bin/bash
+# Stage packages: apt tuning and the tools later stages rely on
+# shellcheck disable=SC1091
+set -e
+source /opt/ds-aws/bootstrap/lib.sh
+
+NUM_CORES=$(nproc)
+
+log "Configuring apt for parallel downloads"
+cat > /etc/apt/apt.conf.d/80parallel-downloads << EOF_APT
+Acquire::Queue-Mode "host";
+Acquire::http::Pipeline-Depth "5";
+Acquire::http::Timeout "180";
+Acquire::https::Pipeline-Depth "5";
+Acquire::https::Timeout "180";
+Acquire::http::Dl-Limit "50000";
+Acquire::https::Dl-Limit "50000";
+EOF_APT
+
+log "Configuring parallel make operations"
+cat > /etc/makepkg.conf << EOF_MAKE
+MAKEFLAGS="-j$NUM_CORES"
+EOF_MAKE
+
+log "Installing dependencies with $NUM_CORES cores"
+DEBIAN_FRONTEND=noninteractive apt-get update
+DEBIAN_FRONTEND=noninteractive apt-get install -y \
+    --no-install-recommends \
+    -o Dpkg::Options::="--force-confdef" \
+    -o Dpkg::Options::="--force-confold" \
+    -o Acquire::Retries="3" \
+    -o DPkg::MaxProcs="$NUM_CORES" \
+    apt-transport-https \
+    ca-certificates \
+    curl \
+    software-properties-common \
+    git \
+    make \
+    parallel \
+    jq
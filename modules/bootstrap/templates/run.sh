@@ -0,0 +1,41 @@
+#!/bin/bash
+# Bootstrap runner, started once by cloud-init. Runs every stage in order and
+# skips the ones that already completed, so after a failure it can be rerun
+# with: sudo /opt/ds-aws/bootstrap/run.sh [STAGE...]
+# shellcheck disable=SC1091
+set -e
+source /opt/ds-aws/bootstrap/lib.sh
+
+touch /var/log/deploy.log /var/log/model-pull.log
+chmod 666 /var/log/deploy.log /var/log/model-pull.log
+
+# Stages given on the command line are run on their own
+if [ $# -gt 0 ]; then
+    for stage in "$@"; do
+        run_stage "$stage"
+    done
+    exit 0
+fi
+
+exec > >(log_output | tee -a /var/log/deploy.log) 2>&1
+
+log "Starting deployment ${deployment_id} (${node_role}) with $(nproc) cores"
+
+run_stage cloudwatch
+run_stage packages
+run_stage docker
+%{ if enable_autoscaling ~}
+run_stage claim
+%{ endif ~}
+run_stage app
+%{ if node_role != "web" ~}
+
+# The model pull takes hours for large models; it continues in the background
+# and logs the completion once the models are warmed up
+log "Starting model stage in background"
+nohup "$BOOTSTRAP_DIR/run.sh" models > /dev/null 2>&1 &
+log "Base stages completed, waiting for the model stage"
+%{ else ~}
+
+log "Deployment completed"
+%{ endif ~}
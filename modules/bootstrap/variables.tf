@@ -0,0 +1,4 @@
+variable "template_vars" {
+  description = "Variables the bootstrap templates are rendered with; see the compute module's user_data_vars for the full set"
+  type        = any
+}
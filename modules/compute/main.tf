@@ -0,0 +1,606 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+locals {
+  # Rendered into every bootstrap stage; see modules/bootstrap
+  user_data_vars = {
+    node_role                 = local.pool_enabled ? "web" : "standalone"
+    deployment_id             = var.name_prefix
+    log_group_name            = var.log_group_name
+    app_log_stream            = var.app_log_stream
+    model_pull_log_group_name = var.model_pull_log_group_name
+    model_pull_stream         = var.model_pull_stream
+    github_token              = var.github_token
+    aws_region                = var.region
+    ollama_model              = var.ollama_model
+
+    ollama_keep_alive        = var.ollama_keep_alive
+    ollama_num_parallel      = var.ollama_num_parallel
+    ollama_max_loaded_models = var.ollama_max_loaded_models
+    ollama_context_length    = var.ollama_context_length
+    ollama_num_threads       = var.ollama_num_threads
+    ollama_warmup_models     = var.ollama_warmup_models
+
+    enable_api_proxy          = var.enable_api_proxy
+    api_proxy_port            = var.api_proxy_port
+    api_proxy_tls_mode        = var.api_proxy_tls_mode
+    api_proxy_domain          = var.api_proxy_domain
+    api_proxy_acme_email      = var.api_proxy_acme_email
+    api_proxy_auth_mode       = var.api_proxy_auth_mode
+    api_proxy_basic_auth_user = var.api_proxy_basic_auth_user
+    api_proxy_secret_arn      = var.api_proxy_secret_arn
+    dns_hostname              = var.dns_hostname
+
+    model_cache_bucket = var.model_cache_bucket
+    model_cache_prefix = var.model_cache_prefix
+
+    model_volume_id = local.model_volume_enabled ? aws_ebs_volume.models[0].id : ""
+
+    enable_autoscaling     = var.enable_autoscaling
+    autoscaling_group_name = local.autoscaling_group_name
+    lifecycle_hook_name    = local.launch_hook_name
+    eip_allocation_id      = var.create_eip && var.enable_autoscaling ? aws_eip.app[0].allocation_id : ""
+
+    inference_lb_dns_name = local.pool_enabled ? aws_lb.inference[0].dns_name : ""
+  }
+
+  pool_enabled = var.replica_count > 0
+  pool_is_alb  = local.pool_enabled && var.inference_lb_type == "application"
+
+  autoscaling_group_name = "${var.name_prefix}-asg"
+  launch_hook_name       = "${var.name_prefix}-claim-resources"
+
+  # Empty when replacements may come up on any address (autoscaling without an Elastic IP)
+  public_ip = var.create_eip ? aws_eip.app[0].public_ip : (
+    var.enable_autoscaling ? "" : aws_instance.app[0].public_ip
+  )
+
+  # Hostnames pointed at the instance; the proxy may have a name of its own
+  dns_records = toset(compact(var.route53_zone_id == "" ? [] : [
+    var.dns_hostname,
+    var.enable_api_proxy ? var.api_proxy_domain : "",
+  ]))
+
+  model_volume_from_snapshot = var.model_volume_snapshot_id != "" || length(var.model_volume_snapshot_tags) > 0
+  model_volume_enabled       = local.model_volume_from_snapshot || var.model_volume_size > 0
+  model_volume_snapshot_id = var.model_volume_snapshot_id != "" ? var.model_volume_snapshot_id : (
+    length(var.model_volume_snapshot_tags) > 0 ? data.aws_ebs_snapshot.models[0].id : null
+  )
+}
+
+# Bootstrap stages of the OpenWebUI (or standalone) instance
+module "bootstrap" {
+  source = "../bootstrap"
+
+  template_vars = local.user_data_vars
+}
+
+# Pool nodes only run Ollama; each ships logs to its own streams
+module "inference_bootstrap" {
+  source = "../bootstrap"
+  count  = local.pool_enabled ? 1 : 0
+
+  template_vars = merge(local.user_data_vars, {
+    node_role          = "inference"
+    app_log_stream     = "${var.name_prefix}-inference-{instance_id}"
+    model_pull_stream  = "${var.name_prefix}-inference-{instance_id}-model-pull"
+    enable_api_proxy   = false
+    enable_autoscaling = false
+    eip_allocation_id  = ""
+    model_volume_id    = ""
+  })
+}
+
+resource "aws_security_group" "app" {
+  name        = "${var.name_prefix}-sg"
+  description = "Security group for ${var.name_prefix}"
+  vpc_id      = var.vpc_id
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = var.ssh_cidr_blocks
+    description = "SSH"
+  }
+
+  ingress {
+    from_port   = 8080
+    to_port     = 8080
+    protocol    = "tcp"
+    cidr_blocks = var.ingress_cidr_blocks
+    description = "OpenWebUI"
+  }
+
+  # Ollama is only reachable directly when the API proxy is disabled
+  dynamic "ingress" {
+    for_each = var.enable_api_proxy ? [] : [11434]
+    content {
+      from_port   = ingress.value
+      to_port     = ingress.value
+      protocol    = "tcp"
+      cidr_blocks = var.ingress_cidr_blocks
+      description = "Ollama"
+    }
+  }
+
+  dynamic "ingress" {
+    for_each = var.enable_api_proxy ? [var.api_proxy_port] : []
+    content {
+      from_port   = ingress.value
+      to_port     = ingress.value
+      protocol    = "tcp"
+      cidr_blocks = var.ingress_cidr_blocks
+      description = "Ollama API proxy"
+    }
+  }
+
+  # ACME HTTP-01 challenges are answered on port 80
+  dynamic "ingress" {
+    for_each = var.enable_api_proxy && var.api_proxy_tls_mode == "acme" ? [80] : []
+    content {
+      from_port   = ingress.value
+      to_port     = ingress.value
+      protocol    = "tcp"
+      cidr_blocks = ["0.0.0.0/0"]
+      description = "ACME HTTP challenge"
+    }
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-sg"
+  })
+}
+
+resource "aws_key_pair" "app" {
+  key_name   = "${var.name_prefix}-key"
+  public_key = var.ssh_public_key
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-key"
+  })
+
+  lifecycle {
+    precondition {
+      condition     = var.ssh_public_key != ""
+      error_message = "An SSH key is required: set ssh_public_key, ssh_public_key_path or generate_ssh_key."
+    }
+  }
+}
+
+resource "aws_instance" "app" {
+  count = var.enable_autoscaling ? 0 : 1
+
+  ami                         = var.ami_id
+  instance_type               = var.instance_type
+  subnet_id                   = var.subnet_id
+  vpc_security_group_ids      = [aws_security_group.app.id]
+  associate_public_ip_address = true
+  iam_instance_profile        = var.instance_profile_name
+  key_name                    = aws_key_pair.app.key_name
+
+  root_block_device {
+    volume_size = 1000
+    volume_type = "gp3"
+    encrypted   = true
+    tags = merge(var.tags, {
+      Name = "${var.name_prefix}-volume"
+    })
+  }
+
+  metadata_options {
+    http_tokens = "required"
+  }
+
+  tags = merge(var.tags, {
+    Name    = "${var.name_prefix}-instance"
+    Purpose = "ollama-inference"
+  })
+
+  user_data_base64 = module.bootstrap.user_data
+
+  lifecycle {
+    precondition {
+      condition     = !var.enable_api_proxy || var.api_proxy_tls_mode != "acme" || var.api_proxy_domain != ""
+      error_message = "api_proxy_domain must be set when api_proxy_tls_mode is acme."
+    }
+
+    precondition {
+      condition     = length(var.ollama_warmup_models) <= var.ollama_max_loaded_models
+      error_message = "ollama_max_loaded_models must allow every model in ollama_warmup_models to stay loaded."
+    }
+  }
+}
+
+# Inference pool mode: aws_instance.app serves OpenWebUI (and the API proxy)
+# and relays Ollama traffic to replica_count Ollama-only instances behind an
+# internal load balancer
+resource "aws_security_group" "inference" {
+  count       = local.pool_enabled ? 1 : 0
+  name        = "${var.name_prefix}-inference-sg"
+  description = "Ollama inference pool for ${var.name_prefix}"
+  vpc_id      = var.vpc_id
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = var.ssh_cidr_blocks
+    description = "SSH"
+  }
+
+  # Load balancer traffic and health checks; NLBs preserve the client address
+  ingress {
+    from_port   = 11434
+    to_port     = 11434
+    protocol    = "tcp"
+    cidr_blocks = [var.vpc_cidr_block]
+    description = "Ollama from the VPC"
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-inference-sg"
+  })
+}
+
+resource "aws_instance" "inference" {
+  count                       = var.replica_count
+  ami                         = var.ami_id
+  instance_type               = var.inference_instance_type != "" ? var.inference_instance_type : var.instance_type
+  subnet_id                   = var.subnet_id
+  vpc_security_group_ids      = [aws_security_group.inference[0].id]
+  associate_public_ip_address = true
+  iam_instance_profile        = var.instance_profile_name
+  key_name                    = aws_key_pair.app.key_name
+
+  root_block_device {
+    volume_size = 1000
+    volume_type = "gp3"
+    encrypted   = true
+    tags = merge(var.tags, {
+      Name = "${var.name_prefix}-inference-${count.index}-volume"
+    })
+  }
+
+  metadata_options {
+    http_tokens = "required"
+  }
+
+  tags = merge(var.tags, {
+    Name    = "${var.name_prefix}-inference-${count.index}"
+    Purpose = "ollama-inference"
+  })
+
+  user_data_base64 = module.inference_bootstrap[0].user_data
+
+  lifecycle {
+    precondition {
+      condition     = !var.enable_autoscaling && !local.model_volume_enabled
+      error_message = "replica_count cannot be combined with enable_autoscaling or a model volume; share models through the S3 model cache instead."
+    }
+  }
+}
+
+resource "aws_security_group" "inference_lb" {
+  count       = local.pool_is_alb ? 1 : 0
+  name        = "${var.name_prefix}-inference-lb-sg"
+  description = "Internal Ollama load balancer for ${var.name_prefix}"
+  vpc_id      = var.vpc_id
+
+  ingress {
+    from_port   = 11434
+    to_port     = 11434
+    protocol    = "tcp"
+    cidr_blocks = [var.vpc_cidr_block]
+    description = "Ollama from the VPC"
+  }
+
+  egress {
+    from_port   = 11434
+    to_port     = 11434
+    protocol    = "tcp"
+    cidr_blocks = [var.vpc_cidr_block]
+    description = "Ollama pool"
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-inference-lb-sg"
+  })
+}
+
+resource "aws_lb" "inference" {
+  count              = local.pool_enabled ? 1 : 0
+  name               = "${var.name_prefix}-ollama"
+  internal           = true
+  load_balancer_type = var.inference_lb_type
+  subnets            = concat([var.subnet_id], var.secondary_subnet_ids)
+  security_groups    = local.pool_is_alb ? [aws_security_group.inference_lb[0].id] : null
+
+  # Non-streamed generations on large models easily exceed the 60s default
+  idle_timeout = local.pool_is_alb ? 3600 : null
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-ollama"
+  })
+}
+
+resource "aws_lb_target_group" "inference" {
+  count    = local.pool_enabled ? 1 : 0
+  name     = "${var.name_prefix}-ollama"
+  port     = 11434
+  protocol = local.pool_is_alb ? "HTTP" : "TCP"
+  vpc_id   = var.vpc_id
+
+  # Ollama answers before the model is pulled; the pool fills as nodes finish
+  health_check {
+    protocol            = "HTTP"
+    path                = "/api/tags"
+    matcher             = "200"
+    interval            = 30
+    healthy_threshold   = 2
+    unhealthy_threshold = 3
+  }
+
+  stickiness {
+    enabled = var.inference_sticky_sessions
+    type    = local.pool_is_alb ? "lb_cookie" : "source_ip"
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-ollama"
+  })
+}
+
+resource "aws_lb_target_group_attachment" "inference" {
+  count            = var.replica_count
+  target_group_arn = aws_lb_target_group.inference[0].arn
+  target_id        = aws_instance.inference[count.index].id
+  port             = 11434
+}
+
+resource "aws_lb_listener" "inference" {
+  count             = local.pool_enabled ? 1 : 0
+  load_balancer_arn = aws_lb.inference[0].arn
+  port              = 11434
+  protocol          = local.pool_is_alb ? "HTTP" : "TCP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = aws_lb_target_group.inference[0].arn
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-ollama"
+  })
+}
+
+# Autoscaling mode: the same instance, launched and replaced by an ASG
+resource "aws_launch_template" "app" {
+  count         = var.enable_autoscaling ? 1 : 0
+  name_prefix   = "${var.name_prefix}-"
+  image_id      = var.ami_id
+  instance_type = var.instance_type
+  key_name      = aws_key_pair.app.key_name
+  user_data     = module.bootstrap.user_data
+
+  iam_instance_profile {
+    name = var.instance_profile_name
+  }
+
+  network_interfaces {
+    device_index                = 0
+    associate_public_ip_address = true
+    security_groups             = [aws_security_group.app.id]
+    delete_on_termination       = true
+  }
+
+  block_device_mappings {
+    device_name = var.root_device_name
+
+    ebs {
+      volume_size           = 1000
+      volume_type           = "gp3"
+      encrypted             = true
+      delete_on_termination = true
+    }
+  }
+
+  metadata_options {
+    http_tokens = "required"
+  }
+
+  tag_specifications {
+    resource_type = "instance"
+    tags = merge(var.tags, {
+      Name    = "${var.name_prefix}-instance"
+      Purpose = "ollama-inference"
+    })
+  }
+
+  tag_specifications {
+    resource_type = "volume"
+    tags = merge(var.tags, {
+      Name = "${var.name_prefix}-volume"
+    })
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-launch-template"
+  })
+
+  lifecycle {
+    precondition {
+      condition     = !var.enable_api_proxy || var.api_proxy_tls_mode != "acme" || var.api_proxy_domain != ""
+      error_message = "api_proxy_domain must be set when api_proxy_tls_mode is acme."
+    }
+
+    precondition {
+      condition     = length(var.ollama_warmup_models) <= var.ollama_max_loaded_models
+      error_message = "ollama_max_loaded_models must allow every model in ollama_warmup_models to stay loaded."
+    }
+  }
+}
+
+resource "aws_autoscaling_group" "app" {
+  count                     = var.enable_autoscaling ? 1 : 0
+  name                      = local.autoscaling_group_name
+  min_size                  = var.asg_min_size
+  max_size                  = var.asg_max_size
+  desired_capacity          = var.asg_min_size
+  vpc_zone_identifier       = [var.subnet_id]
+  health_check_type         = var.asg_health_check_type
+  health_check_grace_period = var.asg_health_check_grace_period
+
+  launch_template {
+    id      = aws_launch_template.app[0].id
+    version = aws_launch_template.app[0].latest_version
+  }
+
+  # A new instance stays in Pending:Wait until user_data has attached the
+  # model volume and Elastic IP; it is abandoned and replaced if that fails
+  initial_lifecycle_hook {
+    name                 = local.launch_hook_name
+    lifecycle_transition = "autoscaling:EC2_INSTANCE_LAUNCHING"
+    default_result       = "ABANDON"
+    heartbeat_timeout    = 1800
+  }
+
+  # The model volume and Elastic IP can only belong to one instance, so the
+  # old instance has to go before its replacement can claim them
+  instance_refresh {
+    strategy = "Rolling"
+    preferences {
+      min_healthy_percentage = 0
+    }
+  }
+
+  # Instances and volumes are tagged by the launch template
+  dynamic "tag" {
+    for_each = merge(var.tags, { Name = local.autoscaling_group_name })
+    content {
+      key                 = tag.key
+      value               = tag.value
+      propagate_at_launch = false
+    }
+  }
+
+  lifecycle {
+    precondition {
+      condition     = var.asg_max_size >= var.asg_min_size
+      error_message = "asg_max_size must be at least asg_min_size."
+    }
+
+    precondition {
+      condition     = var.asg_max_size == 1 || !(local.model_volume_enabled || var.create_eip)
+      error_message = "A model volume or Elastic IP can only be claimed by one instance; asg_max_size must be 1."
+    }
+  }
+}
+
+resource "aws_eip" "app" {
+  count  = var.create_eip ? 1 : 0
+  domain = "vpc"
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-eip"
+  })
+}
+
+# In autoscaling mode the instance associates the address itself at launch
+resource "aws_eip_association" "app" {
+  count         = var.create_eip && !var.enable_autoscaling ? 1 : 0
+  instance_id   = aws_instance.app[0].id
+  allocation_id = aws_eip.app[0].allocation_id
+}
+
+resource "aws_route53_record" "app" {
+  for_each = local.dns_records
+  zone_id  = var.route53_zone_id
+  name     = each.value
+  type     = "A"
+  ttl      = 300
+  records  = [local.public_ip]
+
+  lifecycle {
+    precondition {
+      condition     = !var.enable_autoscaling || var.create_eip
+      error_message = "DNS records in autoscaling mode need create_eip, since replacements get new addresses."
+    }
+  }
+}
+
+# Newest completed snapshot of a model store carrying the requested tags
+data "aws_ebs_snapshot" "models" {
+  count       = var.model_volume_snapshot_id == "" && length(var.model_volume_snapshot_tags) > 0 ? 1 : 0
+  most_recent = true
+  owners      = ["self"]
+
+  filter {
+    name   = "status"
+    values = ["completed"]
+  }
+
+  dynamic "filter" {
+    for_each = var.model_volume_snapshot_tags
+    content {
+      name   = "tag:${filter.key}"
+      values = [filter.value]
+    }
+  }
+}
+
+# Fast Snapshot Restore avoids lazy loading of blocks from S3, so the first
+# model load reads at full volume speed. It is billed per snapshot and AZ.
+resource "aws_ebs_fast_snapshot_restore" "models" {
+  count             = local.model_volume_from_snapshot && var.model_volume_fast_snapshot_restore ? 1 : 0
+  availability_zone = var.availability_zone
+  snapshot_id       = local.model_volume_snapshot_id
+}
+
+resource "aws_ebs_volume" "models" {
+  count             = local.model_volume_enabled ? 1 : 0
+  availability_zone = var.availability_zone
+  snapshot_id       = local.model_volume_snapshot_id
+  size              = var.model_volume_size > 0 ? var.model_volume_size : null
+  type              = "gp3"
+  throughput        = var.model_volume_throughput
+  encrypted         = true
+
+  tags = merge(var.tags, {
+    Name    = "${var.name_prefix}-models"
+    Purpose = "ollama-models"
+  })
+
+  depends_on = [aws_ebs_fast_snapshot_restore.models]
+}
+
+# In autoscaling mode the instance attaches the volume itself at launch
+resource "aws_volume_attachment" "models" {
+  count       = local.model_volume_enabled && !var.enable_autoscaling ? 1 : 0
+  device_name = "/dev/sdf"
+  volume_id   = aws_ebs_volume.models[0].id
+  instance_id = aws_instance.app[0].id
+
+  # Stop Ollama's writes before the volume goes away on destroy
+  stop_instance_before_detaching = true
+}
@@ -0,0 +1,54 @@
+output "instance_id" {
+  description = "ID of the EC2 instance (empty in autoscaling mode)"
+  value       = var.enable_autoscaling ? "" : aws_instance.app[0].id
+}
+
+output "public_ip" {
+  description = "Public IP address of the instance (empty in autoscaling mode without an Elastic IP)"
+  value       = local.public_ip
+}
+
+output "autoscaling_group_name" {
+  description = "Auto Scaling group running the instance (empty unless enable_autoscaling is set)"
+  value       = var.enable_autoscaling ? local.autoscaling_group_name : ""
+}
+
+output "security_group_id" {
+  description = "Security group of the OpenWebUI instance"
+  value       = aws_security_group.app.id
+}
+
+output "eip_allocation_id" {
+  description = "Allocation ID of the Elastic IP (empty unless create_eip is set)"
+  value       = var.create_eip ? aws_eip.app[0].allocation_id : ""
+}
+
+output "model_volume_id" {
+  description = "EBS volume holding the Ollama model store (empty when models live on the root volume)"
+  value       = one(aws_ebs_volume.models[*].id)
+}
+
+output "model_volume_arn" {
+  description = "ARN of the model volume (empty when models live on the root volume)"
+  value       = local.model_volume_enabled ? aws_ebs_volume.models[0].arn : ""
+}
+
+output "model_volume_snapshot_id" {
+  description = "Snapshot the model volume was created from (empty when it was not)"
+  value       = local.model_volume_snapshot_id
+}
+
+output "inference_lb_dns_name" {
+  description = "DNS name of the internal load balancer in front of the inference pool (empty when replica_count is 0)"
+  value       = local.pool_enabled ? aws_lb.inference[0].dns_name : ""
+}
+
+output "inference_instance_ids" {
+  description = "IDs of the inference pool instances"
+  value       = aws_instance.inference[*].id
+}
+
+output "dns_records" {
+  description = "Route 53 A records pointing at the instance"
+  value       = sort([for r in aws_route53_record.app : r.fqdn])
+}
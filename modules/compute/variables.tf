@@ -0,0 +1,309 @@
+variable "name_prefix" {
+  description = "Prefix of every resource name, unique per deployment"
+  type        = string
+}
+
+variable "tags" {
+  description = "Tags applied to every taggable resource, under each resource's own Name tag"
+  type        = map(string)
+  default     = {}
+}
+
+variable "region" {
+  description = "AWS region the instances call AWS APIs in"
+  type        = string
+}
+
+variable "ami_id" {
+  description = "AMI ID for the EC2 instance (Ubuntu 22.04 LTS)"
+  type        = string
+}
+
+variable "instance_type" {
+  description = "EC2 instance type for the application"
+  type        = string
+}
+
+variable "ssh_public_key" {
+  description = "Public SSH key (OpenSSH format) installed for the ubuntu user"
+  type        = string
+}
+
+variable "root_device_name" {
+  description = "Root device name of the AMI, which the launch template sizes the root volume by"
+  type        = string
+  default     = "/dev/sda1"
+}
+
+variable "vpc_id" {
+  description = "VPC the security groups and load balancer are created in"
+  type        = string
+}
+
+variable "vpc_cidr_block" {
+  description = "CIDR block of the VPC; the inference pool only accepts traffic from it"
+  type        = string
+}
+
+variable "ssh_cidr_blocks" {
+  description = "CIDR blocks allowed to connect over SSH"
+  type        = list(string)
+  default     = ["0.0.0.0/0"]
+}
+
+variable "ingress_cidr_blocks" {
+  description = "CIDR blocks allowed to reach OpenWebUI, the Ollama API and the API proxy"
+  type        = list(string)
+  default     = ["0.0.0.0/0"]
+}
+
+variable "subnet_id" {
+  description = "Public subnet the instances are launched in"
+  type        = string
+}
+
+variable "availability_zone" {
+  description = "Availability zone of subnet_id, where the model volume is created"
+  type        = string
+}
+
+variable "secondary_subnet_ids" {
+  description = "Subnets in other availability zones, added to an application load balancer"
+  type        = list(string)
+  default     = []
+}
+
+variable "instance_profile_name" {
+  description = "Instance profile granting the instances their AWS permissions"
+  type        = string
+}
+
+variable "log_group_name" {
+  description = "CloudWatch Log Group the instances ship logs to"
+  type        = string
+}
+
+variable "app_log_stream" {
+  description = "CloudWatch Log Stream for application logs"
+  type        = string
+}
+
+variable "model_pull_log_group_name" {
+  description = "CloudWatch Log Group the instances ship model pull logs to"
+  type        = string
+}
+
+variable "model_pull_stream" {
+  description = "CloudWatch Log Stream for model pull logs"
+  type        = string
+}
+
+variable "github_token" {
+  description = "GitHub Personal Access Token for repository access"
+  type        = string
+  sensitive   = true
+}
+
+variable "ollama_model" {
+  description = "Ollama model pulled onto the instance after deployment"
+  type        = string
+  default     = "deepseek-r1:671b"
+}
+
+variable "ollama_keep_alive" {
+  description = "How long Ollama keeps an idle model in memory (OLLAMA_KEEP_ALIVE), e.g. 30m or 24h; -1 keeps it loaded forever"
+  type        = string
+  default     = "-1"
+}
+
+variable "ollama_num_parallel" {
+  description = "Requests each loaded model serves concurrently (OLLAMA_NUM_PARALLEL). Every slot reserves its own context memory"
+  type        = number
+  default     = 1
+}
+
+variable "ollama_max_loaded_models" {
+  description = "Models Ollama may hold in memory at once (OLLAMA_MAX_LOADED_MODELS)"
+  type        = number
+  default     = 1
+}
+
+variable "ollama_context_length" {
+  description = "Default context window in tokens (OLLAMA_CONTEXT_LENGTH)"
+  type        = number
+  default     = 8192
+}
+
+variable "ollama_num_threads" {
+  description = "CPU threads used for inference, set as the num_thread default of the served models. 0 uses every core reported by nproc"
+  type        = number
+  default     = 0
+}
+
+variable "ollama_warmup_models" {
+  description = "Models loaded into memory after the pull, before the deployment is reported ready. Models other than ollama_model are pulled first"
+  type        = list(string)
+  default     = []
+}
+
+variable "enable_api_proxy" {
+  description = "Put an authenticating Caddy reverse proxy in front of the Ollama API and bind Ollama to localhost"
+  type        = bool
+  default     = false
+}
+
+variable "api_proxy_port" {
+  description = "Port the Ollama API proxy listens on"
+  type        = number
+  default     = 443
+}
+
+variable "api_proxy_tls_mode" {
+  description = "How the API proxy obtains its certificate: self_signed or acme"
+  type        = string
+  default     = "self_signed"
+}
+
+variable "api_proxy_domain" {
+  description = "Hostname served by the API proxy (required for acme, added as a SAN for self_signed)"
+  type        = string
+  default     = ""
+}
+
+variable "api_proxy_acme_email" {
+  description = "Contact email registered with the ACME CA"
+  type        = string
+  default     = ""
+}
+
+variable "api_proxy_auth_mode" {
+  description = "Authentication enforced by the API proxy: bearer or basic"
+  type        = string
+  default     = "bearer"
+}
+
+variable "api_proxy_basic_auth_user" {
+  description = "Username accepted by the API proxy when api_proxy_auth_mode is basic"
+  type        = string
+  default     = "ollama"
+}
+
+variable "api_proxy_secret_arn" {
+  description = "Secrets Manager secret holding the API token/password the proxy enforces"
+  type        = string
+  default     = ""
+}
+
+variable "model_cache_bucket" {
+  description = "S3 bucket used as the Ollama model cache (empty when disabled)"
+  type        = string
+  default     = ""
+}
+
+variable "model_cache_prefix" {
+  description = "Key prefix under which the model cache is stored in the bucket"
+  type        = string
+  default     = "ollama/models"
+}
+
+variable "model_volume_size" {
+  description = "Size in GiB of a dedicated EBS volume for the Ollama model store. 0 keeps models on the root volume unless a snapshot is given, in which case the snapshot size is used"
+  type        = number
+  default     = 0
+}
+
+variable "model_volume_snapshot_id" {
+  description = "EBS snapshot holding a fully pulled Ollama model store to create the model volume from"
+  type        = string
+  default     = ""
+}
+
+variable "model_volume_snapshot_tags" {
+  description = "Tags identifying model store snapshots owned by this account; the newest match is used when model_volume_snapshot_id is empty"
+  type        = map(string)
+  default     = {}
+}
+
+variable "model_volume_fast_snapshot_restore" {
+  description = "Enable Fast Snapshot Restore for the model snapshot in the instance's availability zone"
+  type        = bool
+  default     = false
+}
+
+variable "model_volume_throughput" {
+  description = "Provisioned throughput of the gp3 model volume in MiB/s"
+  type        = number
+  default     = 500
+}
+
+variable "enable_autoscaling" {
+  description = "Run the instance in an Auto Scaling group that replaces it when it fails health checks, instead of a standalone aws_instance"
+  type        = bool
+  default     = false
+}
+
+variable "asg_min_size" {
+  description = "Minimum (and desired) number of instances in the Auto Scaling group"
+  type        = number
+  default     = 1
+}
+
+variable "asg_max_size" {
+  description = "Maximum number of instances in the Auto Scaling group"
+  type        = number
+  default     = 1
+}
+
+variable "asg_health_check_type" {
+  description = "Health check the Auto Scaling group replaces instances on. Only EC2 is meaningful: the group is not attached to a load balancer"
+  type        = string
+  default     = "EC2"
+}
+
+variable "asg_health_check_grace_period" {
+  description = "Seconds after launch before health checks count, long enough for the bootstrap to finish"
+  type        = number
+  default     = 1800
+}
+
+variable "create_eip" {
+  description = "Allocate an Elastic IP so the public address survives instance replacement"
+  type        = bool
+  default     = false
+}
+
+variable "replica_count" {
+  description = "Number of Ollama-only inference instances behind an internal load balancer. 0 runs Ollama on the OpenWebUI instance"
+  type        = number
+  default     = 0
+}
+
+variable "inference_instance_type" {
+  description = "EC2 instance type of the inference pool. Defaults to instance_type"
+  type        = string
+  default     = ""
+}
+
+variable "inference_lb_type" {
+  description = "Load balancer in front of the inference pool: network or application"
+  type        = string
+  default     = "network"
+}
+
+variable "inference_sticky_sessions" {
+  description = "Pin clients to one inference instance (source IP for network, cookie for application load balancers) so follow-up requests reuse its loaded context"
+  type        = bool
+  default     = false
+}
+
+variable "route53_zone_id" {
+  description = "Route 53 hosted zone in which to create A records for dns_hostname and api_proxy_domain. No records are managed when empty"
+  type        = string
+  default     = ""
+}
+
+variable "dns_hostname" {
+  description = "Fully qualified hostname for the instance, given a Route 53 record and added to the API proxy certificate"
+  type        = string
+  default     = ""
+}
@@ -0,0 +1,159 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+data "aws_partition" "current" {}
+
+data "aws_caller_identity" "current" {}
+
+data "aws_region" "current" {}
+
+locals {
+  arn_prefix = "arn:${data.aws_partition.current.partition}"
+  account_id = data.aws_caller_identity.current.account_id
+  region     = data.aws_region.current.name
+}
+
+data "aws_iam_policy_document" "assume_role" {
+  statement {
+    actions = ["sts:AssumeRole"]
+
+    principals {
+      type        = "Service"
+      identifiers = ["ec2.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "ec2_cloudwatch" {
+  name = "${var.name_prefix}-role"
+
+  assume_role_policy = data.aws_iam_policy_document.assume_role.json
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-role"
+  })
+}
+
+# Instance role permissions are assembled from one document per feature so
+# that disabled features contribute no statements at all.
+data "aws_iam_policy_document" "logs" {
+  statement {
+    sid = "ShipDeploymentLogs"
+    actions = [
+      "logs:CreateLogGroup",
+      "logs:CreateLogStream",
+      "logs:PutLogEvents",
+      "logs:DescribeLogStreams",
+    ]
+    resources = flatten([for arn in var.log_group_arns : [arn, "${arn}:*"]])
+  }
+}
+
+data "aws_iam_policy_document" "api_proxy" {
+  count = var.enable_api_proxy ? 1 : 0
+
+  statement {
+    sid       = "ReadApiProxySecret"
+    actions   = ["secretsmanager:GetSecretValue"]
+    resources = [var.api_proxy_secret_arn]
+  }
+}
+
+data "aws_iam_policy_document" "model_cache" {
+  count = var.enable_model_cache ? 1 : 0
+
+  statement {
+    sid       = "ListModelCache"
+    actions   = ["s3:ListBucket"]
+    resources = ["${local.arn_prefix}:s3:::${var.model_cache_bucket}"]
+
+    condition {
+      test     = "StringLike"
+      variable = "s3:prefix"
+      values   = [var.model_cache_prefix, "${var.model_cache_prefix}/*"]
+    }
+  }
+
+  statement {
+    sid       = "ReadWriteModelCache"
+    actions   = ["s3:GetObject", "s3:PutObject"]
+    resources = ["${local.arn_prefix}:s3:::${var.model_cache_bucket}/${var.model_cache_prefix}/*"]
+  }
+}
+
+# Lets a replacement instance claim the model volume and Elastic IP, then
+# complete the launch lifecycle hook
+data "aws_iam_policy_document" "autoscaling" {
+  count = var.enable_autoscaling ? 1 : 0
+
+  statement {
+    sid       = "CompleteLaunchHook"
+    actions   = ["autoscaling:CompleteLifecycleAction"]
+    resources = ["${local.arn_prefix}:autoscaling:${local.region}:${local.account_id}:autoScalingGroup:*:autoScalingGroupName/${var.autoscaling_group_name}"]
+  }
+
+  dynamic "statement" {
+    for_each = var.claim_model_volume || var.claim_eip ? [1] : []
+    content {
+      sid       = "ClaimFromOwnInstances"
+      actions   = ["ec2:AttachVolume", "ec2:AssociateAddress"]
+      resources = ["${local.arn_prefix}:ec2:${local.region}:${local.account_id}:instance/*"]
+
+      # Name tag the compute module gives the deployment's instances
+      condition {
+        test     = "StringEquals"
+        variable = "aws:ResourceTag/Name"
+        values   = ["${var.name_prefix}-instance"]
+      }
+    }
+  }
+
+  dynamic "statement" {
+    for_each = var.claim_model_volume ? [1] : []
+    content {
+      sid       = "AttachModelVolume"
+      actions   = ["ec2:AttachVolume"]
+      resources = [var.model_volume_arn]
+    }
+  }
+
+  dynamic "statement" {
+    for_each = var.claim_eip ? [1] : []
+    content {
+      sid       = "AssociateElasticIp"
+      actions   = ["ec2:AssociateAddress"]
+      resources = ["${local.arn_prefix}:ec2:${local.region}:${local.account_id}:elastic-ip/${var.eip_allocation_id}"]
+    }
+  }
+}
+
+data "aws_iam_policy_document" "instance" {
+  source_policy_documents = concat(
+    [data.aws_iam_policy_document.logs.json],
+    data.aws_iam_policy_document.api_proxy[*].json,
+    data.aws_iam_policy_document.model_cache[*].json,
+    data.aws_iam_policy_document.autoscaling[*].json,
+  )
+}
+
+resource "aws_iam_role_policy" "cloudwatch_policy" {
+  name = "${var.name_prefix}-policy"
+  role = aws_iam_role.ec2_cloudwatch.id
+
+  policy = data.aws_iam_policy_document.instance.json
+}
+
+resource "aws_iam_instance_profile" "ec2_profile" {
+  name = "${var.name_prefix}-profile"
+  role = aws_iam_role.ec2_cloudwatch.name
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-profile"
+  })
+}
@@ -0,0 +1,18 @@
+output "role_name" {
+  description = "Name of the instance role"
+  value       = aws_iam_role.ec2_cloudwatch.name
+}
+
+output "instance_profile_name" {
+  description = "Instance profile carrying the instance role"
+  value       = aws_iam_instance_profile.ec2_profile.name
+
+  # Instances call AWS APIs from user_data, so they must not launch before
+  # the role has its permissions
+  depends_on = [aws_iam_role_policy.cloudwatch_policy]
+}
+
+output "instance_role_policy" {
+  description = "IAM policy document attached to the instance role"
+  value       = aws_iam_role_policy.cloudwatch_policy.policy
+}
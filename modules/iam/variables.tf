@@ -0,0 +1,81 @@
+variable "name_prefix" {
+  description = "Prefix of every resource name, unique per deployment"
+  type        = string
+}
+
+variable "tags" {
+  description = "Tags applied to every taggable resource, under each resource's own Name tag"
+  type        = map(string)
+  default     = {}
+}
+
+variable "log_group_arns" {
+  description = "Log groups the instances ship their logs to"
+  type        = list(string)
+}
+
+variable "enable_api_proxy" {
+  description = "Grant read access to the API proxy secret"
+  type        = bool
+  default     = false
+}
+
+variable "api_proxy_secret_arn" {
+  description = "Secrets Manager secret holding the API proxy credential"
+  type        = string
+  default     = ""
+}
+
+variable "enable_model_cache" {
+  description = "Grant access to the S3 model cache"
+  type        = bool
+  default     = false
+}
+
+variable "model_cache_bucket" {
+  description = "S3 bucket holding the model cache"
+  type        = string
+  default     = ""
+}
+
+variable "model_cache_prefix" {
+  description = "Key prefix of the model cache in the bucket"
+  type        = string
+  default     = "ollama/models"
+}
+
+variable "enable_autoscaling" {
+  description = "Grant what an Auto Scaling group instance needs to complete its launch lifecycle hook"
+  type        = bool
+  default     = false
+}
+
+variable "autoscaling_group_name" {
+  description = "Name of the Auto Scaling group whose lifecycle hook the instances complete"
+  type        = string
+  default     = ""
+}
+
+variable "claim_model_volume" {
+  description = "Let autoscaling instances attach model_volume_arn to themselves"
+  type        = bool
+  default     = false
+}
+
+variable "model_volume_arn" {
+  description = "ARN of the persistent model volume"
+  type        = string
+  default     = ""
+}
+
+variable "claim_eip" {
+  description = "Let autoscaling instances associate eip_allocation_id with themselves"
+  type        = bool
+  default     = false
+}
+
+variable "eip_allocation_id" {
+  description = "Allocation ID of the deployment's Elastic IP"
+  type        = string
+  default     = ""
+}
@@ -0,0 +1,82 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+data "aws_availability_zones" "available" {
+  count = var.availability_zone == "" || var.secondary_subnet ? 1 : 0
+  state = "available"
+}
+
+locals {
+  availability_zone = var.availability_zone != "" ? var.availability_zone : data.aws_availability_zones.available[0].names[0]
+}
+
+resource "aws_vpc" "main" {
+  cidr_block           = var.vpc_cidr_block
+  enable_dns_hostnames = true
+  enable_dns_support   = true
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-vpc"
+  })
+}
+
+resource "aws_subnet" "public" {
+  vpc_id                  = aws_vpc.main.id
+  cidr_block              = cidrsubnet(var.vpc_cidr_block, 8, 1)
+  availability_zone       = local.availability_zone
+  map_public_ip_on_launch = true
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-subnet"
+  })
+}
+
+resource "aws_internet_gateway" "main" {
+  vpc_id = aws_vpc.main.id
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-igw"
+  })
+}
+
+resource "aws_route_table" "main" {
+  vpc_id = aws_vpc.main.id
+
+  route {
+    cidr_block = "0.0.0.0/0"
+    gateway_id = aws_internet_gateway.main.id
+  }
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-rt"
+  })
+}
+
+resource "aws_route_table_association" "main" {
+  subnet_id      = aws_subnet.public.id
+  route_table_id = aws_route_table.main.id
+}
+
+# Application load balancers need subnets in at least two availability zones
+resource "aws_subnet" "secondary" {
+  count             = var.secondary_subnet ? 1 : 0
+  vpc_id            = aws_vpc.main.id
+  cidr_block        = cidrsubnet(var.vpc_cidr_block, 8, 2)
+  availability_zone = element(tolist(setsubtract(data.aws_availability_zones.available[0].names, [aws_subnet.public.availability_zone])), 0)
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-subnet-secondary"
+  })
+}
+
+resource "aws_route_table_association" "secondary" {
+  count          = var.secondary_subnet ? 1 : 0
+  subnet_id      = aws_subnet.secondary[0].id
+  route_table_id = aws_route_table.main.id
+}
@@ -0,0 +1,28 @@
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = aws_vpc.main.id
+}
+
+output "vpc_cidr_block" {
+  description = "CIDR block of the VPC"
+  value       = aws_vpc.main.cidr_block
+}
+
+output "public_subnet_id" {
+  description = "ID of the public subnet instances are launched in"
+  value       = aws_subnet.public.id
+
+  # Instances bootstrap from the internet, so they must not launch before the
+  # subnet routes through the internet gateway
+  depends_on = [aws_route_table_association.main]
+}
+
+output "availability_zone" {
+  description = "Availability zone of the public subnet"
+  value       = aws_subnet.public.availability_zone
+}
+
+output "secondary_subnet_ids" {
+  description = "IDs of the subnets in other availability zones (empty unless secondary_subnet is set)"
+  value       = aws_route_table_association.secondary[*].subnet_id
+}
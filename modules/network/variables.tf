@@ -0,0 +1,28 @@
+variable "name_prefix" {
+  description = "Prefix of every resource name, unique per deployment"
+  type        = string
+}
+
+variable "tags" {
+  description = "Tags applied to every taggable resource, under each resource's own Name tag"
+  type        = map(string)
+  default     = {}
+}
+
+variable "vpc_cidr_block" {
+  description = "IPv4 CIDR block of the VPC; the subnets take its second and third /8-longer blocks"
+  type        = string
+  default     = "10.0.0.0/16"
+}
+
+variable "availability_zone" {
+  description = "Availability zone of the public subnet. The first available zone of the region is used when empty"
+  type        = string
+  default     = ""
+}
+
+variable "secondary_subnet" {
+  description = "Create a second subnet in another availability zone, as application load balancers require"
+  type        = bool
+  default     = false
+}
@@ -0,0 +1,185 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+# Retention applies to a whole log group, so each class of stream has its own:
+# deployment logs here, model pull logs below
+resource "aws_cloudwatch_log_group" "app_logs" {
+  name              = "/${var.name_prefix}/logs"
+  retention_in_days = var.log_retention_days.deploy
+
+  tags = merge(var.tags, {
+    Name        = "${var.name_prefix}-logs"
+    Application = var.name_prefix
+  })
+}
+
+resource "aws_cloudwatch_log_stream" "app_log_stream" {
+  name           = "${var.name_prefix}-stream"
+  log_group_name = aws_cloudwatch_log_group.app_logs.name
+}
+
+resource "aws_cloudwatch_log_group" "model_pull_logs" {
+  name              = "/${var.name_prefix}/model-pull"
+  retention_in_days = var.log_retention_days.model_pull
+
+  tags = merge(var.tags, {
+    Name        = "${var.name_prefix}-model-pull-logs"
+    Application = var.name_prefix
+  })
+}
+
+resource "aws_cloudwatch_log_stream" "model_pull_stream" {
+  name           = "${var.name_prefix}-stream-model-pull"
+  log_group_name = aws_cloudwatch_log_group.model_pull_logs.name
+}
+
+# Saved Logs Insights queries over the JSON events the bootstrap logs. Every
+# event has timestamp, deployment_id, stage, level, attempt, duration_ms and
+# message fields; the model stage's outcome is an event "model_pull_result".
+locals {
+  queries = {
+    "Errors" = <<-EOT
+      fields @timestamp, @logStream, stage, attempt, message
+      | filter level = "error"
+      | sort @timestamp desc
+      | limit 200
+    EOT
+
+    "Failed stages" = <<-EOT
+      fields @timestamp, @logStream, stage, attempt, duration_ms, message
+      | filter level = "error" and message like /^Stage \S+ failed after/
+      | sort @timestamp desc
+    EOT
+
+    "Retried attempts" = <<-EOT
+      filter level = "error" and message like / attempt \d+ failed$/
+      | parse message /^(?<operation>.+) attempt \d+ failed$/
+      | stats count(*) as failures, max(attempt) as last_attempt by @logStream, stage, operation
+      | sort failures desc
+    EOT
+
+    "Stage durations" = <<-EOT
+      filter message like /^Stage \S+ completed$/
+      | stats max(duration_ms) as duration_ms by @logStream, stage
+      | sort duration_ms desc
+    EOT
+
+    "Model pull results" = <<-EOT
+      fields @timestamp, @logStream, status, model, source, reason, pull_seconds, probe_ms
+      | filter event = "model_pull_result"
+      | sort @timestamp desc
+    EOT
+
+    "Deployment timeline" = <<-EOT
+      fields @timestamp, @logStream, stage, level, attempt, duration_ms, message
+      | filter ispresent(stage)
+      | sort @timestamp asc
+      | limit 1000
+    EOT
+  }
+}
+
+resource "aws_cloudwatch_query_definition" "this" {
+  for_each = local.queries
+
+  name            = "${var.name_prefix}/${each.key}"
+  log_group_names = [aws_cloudwatch_log_group.app_logs.name, aws_cloudwatch_log_group.model_pull_logs.name]
+  query_string    = each.value
+}
+
+# Alarms notify this topic; subscribe to it directly or through
+# notification_emails
+resource "aws_sns_topic" "notifications" {
+  name = "${var.name_prefix}-notifications"
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-notifications"
+  })
+}
+
+resource "aws_sns_topic_subscription" "email" {
+  for_each = toset(var.notification_emails)
+
+  topic_arn = aws_sns_topic.notifications.arn
+  protocol  = "email"
+  endpoint  = each.value
+}
+
+# Error events counted per deployment. Retries log an error for every failed
+# attempt, so the totals count attempts rather than failed stages.
+locals {
+  metric_namespace = "DsAws/Bootstrap"
+
+  error_metrics = {
+    DeployErrors = {
+      log_group   = aws_cloudwatch_log_group.app_logs.name
+      pattern     = "{ $.level = \"error\" }"
+      description = "Error events logged by the bootstrap stages"
+    }
+    DockerComposeFailures = {
+      log_group   = aws_cloudwatch_log_group.app_logs.name
+      pattern     = "{ $.level = \"error\" && $.message = \"Docker compose attempt *\" }"
+      description = "Failed attempts to start the application with Docker compose"
+    }
+    ModelPullErrors = {
+      log_group   = aws_cloudwatch_log_group.model_pull_logs.name
+      pattern     = "{ $.level = \"error\" }"
+      description = "Error events logged while pulling the model"
+    }
+    ModelPullFailures = {
+      log_group   = aws_cloudwatch_log_group.model_pull_logs.name
+      pattern     = "{ $.level = \"error\" && $.message = \"Failed to pull model after *\" }"
+      description = "Model pulls that gave up after every attempt failed"
+    }
+  }
+}
+
+resource "aws_cloudwatch_log_metric_filter" "errors" {
+  for_each = local.error_metrics
+
+  name           = "${var.name_prefix}-${each.key}"
+  log_group_name = each.value.log_group
+  pattern        = each.value.pattern
+
+  metric_transformation {
+    name      = each.key
+    namespace = local.metric_namespace
+    value     = "1"
+    unit      = "Count"
+    dimensions = {
+      Deployment = "$.deployment_id"
+    }
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "errors" {
+  for_each = local.error_metrics
+
+  alarm_name        = "${var.name_prefix}-${each.key}"
+  alarm_description = "${each.value.description} in ${var.name_prefix}"
+
+  namespace   = local.metric_namespace
+  metric_name = aws_cloudwatch_log_metric_filter.errors[each.key].metric_transformation[0].name
+  dimensions = {
+    Deployment = var.name_prefix
+  }
+  statistic           = "Sum"
+  period              = var.error_alarm_period
+  evaluation_periods  = 1
+  comparison_operator = "GreaterThanOrEqualToThreshold"
+  threshold           = var.error_alarm_threshold
+  treat_missing_data  = "notBreaching"
+
+  alarm_actions = [aws_sns_topic.notifications.arn]
+  ok_actions    = [aws_sns_topic.notifications.arn]
+
+  tags = merge(var.tags, {
+    Name = "${var.name_prefix}-${each.key}"
+  })
+}
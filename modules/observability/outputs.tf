@@ -0,0 +1,44 @@
+output "log_group_name" {
+  description = "CloudWatch Log Group name"
+  value       = aws_cloudwatch_log_group.app_logs.name
+}
+
+output "log_group_arn" {
+  description = "CloudWatch Log Group ARN"
+  value       = aws_cloudwatch_log_group.app_logs.arn
+}
+
+output "model_pull_log_group_name" {
+  description = "CloudWatch Log Group of the model pull logs"
+  value       = aws_cloudwatch_log_group.model_pull_logs.name
+}
+
+output "model_pull_log_group_arn" {
+  description = "CloudWatch Log Group ARN of the model pull logs"
+  value       = aws_cloudwatch_log_group.model_pull_logs.arn
+}
+
+output "app_log_stream" {
+  description = "CloudWatch Log Stream for application logs"
+  value       = aws_cloudwatch_log_stream.app_log_stream.name
+}
+
+output "model_pull_stream" {
+  description = "CloudWatch Log Stream for model pull logs"
+  value       = aws_cloudwatch_log_stream.model_pull_stream.name
+}
+
+output "query_definition_names" {
+  description = "Names of the saved Logs Insights queries, under a folder named after the prefix"
+  value       = [for query in aws_cloudwatch_query_definition.this : query.name]
+}
+
+output "notification_topic_arn" {
+  description = "SNS topic the error alarms notify"
+  value       = aws_sns_topic.notifications.arn
+}
+
+output "error_alarm_names" {
+  description = "CloudWatch alarms raised by error events in the logs"
+  value       = [for alarm in aws_cloudwatch_metric_alarm.errors : alarm.alarm_name]
+}
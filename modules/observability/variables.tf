@@ -0,0 +1,37 @@
+variable "name_prefix" {
+  description = "Prefix of every resource name, unique per deployment"
+  type        = string
+}
+
+variable "tags" {
+  description = "Tags applied to every taggable resource, under each resource's own Name tag"
+  type        = map(string)
+  default     = {}
+}
+
+variable "log_retention_days" {
+  description = "Days to keep the deployment and model pull logs; 0 keeps them forever"
+  type = object({
+    deploy     = optional(number, 30)
+    model_pull = optional(number, 30)
+  })
+  default = {}
+}
+
+variable "notification_emails" {
+  description = "Email addresses subscribed to the alarm notification topic"
+  type        = list(string)
+  default     = []
+}
+
+variable "error_alarm_threshold" {
+  description = "Error events within one alarm period that raise an error alarm"
+  type        = number
+  default     = 1
+}
+
+variable "error_alarm_period" {
+  description = "Seconds over which error events are summed for the error alarms"
+  type        = number
+  default     = 300
+}
@@ -0,0 +1,23 @@
+output "bucket" {
+  description = "S3 bucket holding the state"
+  value       = aws_s3_bucket.state.bucket
+}
+
+output "lock_table_name" {
+  description = "DynamoDB table holding state locks"
+  value       = aws_dynamodb_table.lock.name
+}
+
+output "backend_config" {
+  description = "Partial S3 backend configuration; save it as backend.hcl and run terraform init -backend-config=backend.hcl"
+  value       = <<-EOT
+    bucket         = "${aws_s3_bucket.state.bucket}"
+    key            = "${var.state_key}"
+    region         = "${data.aws_region.current.name}"
+    dynamodb_table = "${aws_dynamodb_table.lock.name}"
+    encrypt        = true
+    %{~if var.kms_key_arn != ""}
+    kms_key_id     = "${var.kms_key_arn}"
+    %{~endif}
+  EOT
+}
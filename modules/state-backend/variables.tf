@@ -0,0 +1,41 @@
+variable "bucket_name" {
+  description = "Name of the state bucket. Defaults to ds-aws-tfstate-<account id>"
+  type        = string
+  default     = ""
+}
+
+variable "tags" {
+  description = "Tags applied to the bucket and lock table, under their own Name and Purpose tags"
+  type        = map(string)
+  default     = {}
+}
+
+variable "lock_table_name" {
+  description = "Name of the DynamoDB table holding state locks"
+  type        = string
+  default     = "ds-aws-tfstate-lock"
+}
+
+variable "state_key" {
+  description = "Object key of the deployment's state, written into backend_config"
+  type        = string
+  default     = "ds_aws/terraform.tfstate"
+}
+
+variable "kms_key_arn" {
+  description = "KMS key to encrypt state with. S3 managed keys (SSE-S3) are used when empty"
+  type        = string
+  default     = ""
+}
+
+variable "noncurrent_version_retention_days" {
+  description = "Days previous state versions are kept before they expire"
+  type        = number
+  default     = 90
+}
+
+variable "force_destroy" {
+  description = "Allow destroying the state bucket while it still holds state"
+  type        = bool
+  default     = false
+}
@@ -0,0 +1,167 @@
+# State addresses from before the resources moved into submodules. Together
+# with availability_zone = "us-west-1b", existing deployments keep their
+# resources, except for two that are replaced: the model pull stream, which
+# moves to its own log group and loses its history, and the instance, whose
+# root volume becomes encrypted. The README's Module Layout section has the
+# details.
+
+moved {
+  from = aws_cloudwatch_log_group.app_logs
+  to   = module.observability.aws_cloudwatch_log_group.app_logs
+}
+
+moved {
+  from = aws_cloudwatch_log_stream.app_log_stream
+  to   = module.observability.aws_cloudwatch_log_stream.app_log_stream
+}
+
+moved {
+  from = aws_cloudwatch_log_stream.model_pull_stream
+  to   = module.observability.aws_cloudwatch_log_stream.model_pull_stream
+}
+
+moved {
+  from = aws_vpc.main
+  to   = module.network.aws_vpc.main
+}
+
+moved {
+  from = aws_subnet.public
+  to   = module.network.aws_subnet.public
+}
+
+moved {
+  from = aws_internet_gateway.main
+  to   = module.network.aws_internet_gateway.main
+}
+
+moved {
+  from = aws_route_table.main
+  to   = module.network.aws_route_table.main
+}
+
+moved {
+  from = aws_route_table_association.main
+  to   = module.network.aws_route_table_association.main
+}
+
+moved {
+  from = aws_subnet.secondary
+  to   = module.network.aws_subnet.secondary
+}
+
+moved {
+  from = aws_route_table_association.secondary
+  to   = module.network.aws_route_table_association.secondary
+}
+
+moved {
+  from = aws_iam_role.ec2_cloudwatch
+  to   = module.iam.aws_iam_role.ec2_cloudwatch
+}
+
+moved {
+  from = aws_iam_role_policy.cloudwatch_policy
+  to   = module.iam.aws_iam_role_policy.cloudwatch_policy
+}
+
+moved {
+  from = aws_iam_instance_profile.ec2_profile
+  to   = module.iam.aws_iam_instance_profile.ec2_profile
+}
+
+moved {
+  from = aws_security_group.app
+  to   = module.compute.aws_security_group.app
+}
+
+moved {
+  from = aws_key_pair.app
+  to   = module.compute.aws_key_pair.app
+}
+
+moved {
+  from = aws_security_group.inference
+  to   = module.compute.aws_security_group.inference
+}
+
+moved {
+  from = aws_instance.inference
+  to   = module.compute.aws_instance.inference
+}
+
+moved {
+  from = aws_security_group.inference_lb
+  to   = module.compute.aws_security_group.inference_lb
+}
+
+moved {
+  from = aws_lb.inference
+  to   = module.compute.aws_lb.inference
+}
+
+moved {
+  from = aws_lb_target_group.inference
+  to   = module.compute.aws_lb_target_group.inference
+}
+
+moved {
+  from = aws_lb_target_group_attachment.inference
+  to   = module.compute.aws_lb_target_group_attachment.inference
+}
+
+moved {
+  from = aws_lb_listener.inference
+  to   = module.compute.aws_lb_listener.inference
+}
+
+moved {
+  from = aws_launch_template.app
+  to   = module.compute.aws_launch_template.app
+}
+
+moved {
+  from = aws_autoscaling_group.app
+  to   = module.compute.aws_autoscaling_group.app
+}
+
+moved {
+  from = aws_eip.app
+  to   = module.compute.aws_eip.app
+}
+
+moved {
+  from = aws_eip_association.app
+  to   = module.compute.aws_eip_association.app
+}
+
+moved {
+  from = aws_route53_record.app
+  to   = module.compute.aws_route53_record.app
+}
+
+moved {
+  from = aws_ebs_fast_snapshot_restore.models
+  to   = module.compute.aws_ebs_fast_snapshot_restore.models
+}
+
+moved {
+  from = aws_ebs_volume.models
+  to   = module.compute.aws_ebs_volume.models
+}
+
+moved {
+  from = aws_volume_attachment.models
+  to   = module.compute.aws_volume_attachment.models
+}
+
+# aws_instance.app gained a count with the autoscaling mode
+moved {
+  from = aws_instance.app
+  to   = aws_instance.app[0]
+}
+
+moved {
+  from = aws_instance.app[0]
+  to   = module.compute.aws_instance.app[0]
+}
@@ -3,52 +3,156 @@ output "deployment_id" {
   value       = local.name_prefix
 }
 
+output "region" {
+  description = "AWS region the deployment runs in"
+  value       = local.region
+}
+
 output "public_ip" {
-  description = "Public IP address of the EC2 instance"
-  value       = aws_instance.app.public_ip
+  description = "Public IP address of the EC2 instance (empty in autoscaling mode without an Elastic IP)"
+  value       = module.compute.public_ip
+}
+
+output "instance_id" {
+  description = "ID of the EC2 instance (empty in autoscaling mode)"
+  value       = module.compute.instance_id
+}
+
+output "autoscaling_group_name" {
+  description = "Auto Scaling group running the instance (empty unless enable_autoscaling is set)"
+  value       = module.compute.autoscaling_group_name
 }
 
 output "openwebui_url" {
-  description = "URL for OpenWebUI interface"
-  value       = "http://${aws_instance.app.public_ip}:8080"
+  description = "URL for OpenWebUI interface (empty in autoscaling mode without an Elastic IP or dns_hostname; dsctl tunnel finds the group's instance)"
+  value       = local.public_host != "" ? "http://${local.public_host}:8080" : ""
 }
 
 output "ollama_api_url" {
-  description = "URL for Ollama API"
-  value       = "http://${aws_instance.app.public_ip}:11434"
+  description = "URL for Ollama API (empty when no address is known, as for openwebui_url)"
+  value = (
+    var.enable_api_proxy && var.api_proxy_domain != "" ? "https://${var.api_proxy_domain}:${var.api_proxy_port}" :
+    local.public_host == "" ? "" :
+    var.enable_api_proxy ? "https://${local.public_host}:${var.api_proxy_port}" : "http://${local.public_host}:11434"
+  )
+}
+
+output "ollama_model" {
+  description = "Ollama model pulled onto the instance"
+  value       = var.ollama_model
+}
+
+output "api_proxy_secret_arn" {
+  description = "Secrets Manager secret holding the Ollama API proxy credential (empty when the proxy is disabled)"
+  value       = local.api_proxy_secret_arn
 }
 
 output "ssh_command" {
-  description = "Command to SSH into the instance"
-  value       = "ssh -i ${var.ssh_private_key_path} ubuntu@${aws_instance.app.public_ip}"
+  description = "Command to SSH into the instance (empty when no address is known; use dsctl ssh)"
+  value       = local.ssh
+}
+
+output "ssh_private_key_path" {
+  description = "Identity file the ssh outputs use (empty when none was given)"
+  value       = local.ssh_private_key_path
+}
+
+output "ssh_private_key_secret_arn" {
+  description = "Secrets Manager secret holding the generated private SSH key (empty unless ssh_private_key_storage is secrets_manager)"
+  value       = local.ssh_key_in_secret ? aws_secretsmanager_secret.ssh_private_key[0].arn : ""
 }
 
 output "tail_deploy_logs" {
-  description = "Command to tail deployment logs"
-  value       = "ssh -i ${var.ssh_private_key_path} ubuntu@${aws_instance.app.public_ip} 'sudo tail -f /var/log/deploy.log'"
+  description = "Command to tail deployment logs (empty when no address is known; use dsctl logs)"
+  value       = local.ssh != "" ? "${local.ssh} 'sudo tail -f /var/log/deploy.log'" : ""
 }
 
 output "tail_model_pull_logs" {
-  description = "Command to tail model pull logs"
-  value       = "ssh -i ${var.ssh_private_key_path} ubuntu@${aws_instance.app.public_ip} 'sudo tail -f /var/log/model-pull.log'"
+  description = "Command to tail model pull logs (empty when no address is known; use dsctl logs model-pull)"
+  value       = local.ssh != "" ? "${local.ssh} 'sudo tail -f /var/log/model-pull.log'" : ""
 }
 
 output "cloudwatch_logs_url" {
   description = "URL to CloudWatch Logs in AWS Console"
-  value       = "https://${var.aws_region}.console.aws.amazon.com/cloudwatch/home?region=${var.aws_region}#logsV2:log-groups/${aws_cloudwatch_log_group.app_logs.name}"
+  value       = "https://${local.region}.console.aws.amazon.com/cloudwatch/home?region=${local.region}#logsV2:log-groups/${module.observability.log_group_name}"
 }
 
 output "log_group_name" {
   description = "CloudWatch Log Group name"
-  value       = aws_cloudwatch_log_group.app_logs.name
+  value       = module.observability.log_group_name
+}
+
+output "log_group_arn" {
+  description = "CloudWatch Log Group ARN"
+  value       = module.observability.log_group_arn
+}
+
+output "model_pull_log_group_name" {
+  description = "CloudWatch Log Group of the model pull logs"
+  value       = module.observability.model_pull_log_group_name
+}
+
+output "model_pull_log_group_arn" {
+  description = "CloudWatch Log Group ARN of the model pull logs"
+  value       = module.observability.model_pull_log_group_arn
+}
+
+output "instance_role_policy" {
+  description = "IAM policy document attached to the instance role"
+  value       = module.iam.instance_role_policy
 }
 
 output "app_log_stream" {
   description = "CloudWatch Log Stream for application logs"
-  value       = aws_cloudwatch_log_stream.app_log_stream.name
+  value       = module.observability.app_log_stream
 }
 
 output "model_pull_stream" {
   description = "CloudWatch Log Stream for model pull logs"
-  value       = aws_cloudwatch_log_stream.model_pull_stream.name
+  value       = module.observability.model_pull_stream
+}
+
+output "notification_topic_arn" {
+  description = "SNS topic notified when an error alarm changes state"
+  value       = module.observability.notification_topic_arn
+}
+
+output "error_alarm_names" {
+  description = "CloudWatch alarms on error events in the deployment and model pull logs"
+  value       = module.observability.error_alarm_names
+}
+
+output "log_query_names" {
+  description = "Saved CloudWatch Logs Insights queries over the bootstrap's JSON log events"
+  value       = module.observability.query_definition_names
+}
+
+output "model_cache_bucket" {
+  description = "S3 bucket holding the Ollama model cache (empty when disabled)"
+  value       = local.model_cache_bucket
+}
+
+output "model_volume_id" {
+  description = "EBS volume holding the Ollama model store (empty when models live on the root volume)"
+  value       = module.compute.model_volume_id
+}
+
+output "model_volume_snapshot_id" {
+  description = "Snapshot the model volume was created from (empty when it was not)"
+  value       = module.compute.model_volume_snapshot_id
+}
+
+output "inference_ollama_url" {
+  description = "Internal load-balanced Ollama URL of the inference pool (empty when replica_count is 0)"
+  value       = var.replica_count > 0 ? "http://${module.compute.inference_lb_dns_name}:11434" : ""
+}
+
+output "inference_instance_ids" {
+  description = "IDs of the inference pool instances"
+  value       = module.compute.inference_instance_ids
+}
+
+output "dns_records" {
+  description = "Route 53 A records pointing at the instance"
+  value       = module.compute.dns_records
 }
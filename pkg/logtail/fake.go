@@ -0,0 +1,411 @@
+package logtail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+const defaultFakePageSize = 100
+
+// Fake is an in-memory CloudWatch Logs for tests of code that reads a
+// deployment's logs. It implements API and LiveTailAPI over the events added
+// with Put. Filter patterns are limited to terms, quoted phrases, -excluded
+// terms and JSON patterns joining = and != comparisons with &&. The zero
+// value has no log groups and is safe for concurrent use.
+type Fake struct {
+	// PageSize is how many events FilterLogEvents returns at a time.
+	// Default 100.
+	PageSize int
+
+	mu       sync.Mutex
+	groups   map[string][]types.FilteredLogEvent
+	lastID   int
+	calls    int
+	sessions map[*fakeSession]bool
+}
+
+// AddGroup creates an empty log group.
+func (f *Fake) AddGroup(group string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.groups == nil {
+		f.groups = map[string][]types.FilteredLogEvent{}
+	}
+	if _, ok := f.groups[group]; !ok {
+		f.groups[group] = nil
+	}
+}
+
+// Put logs message to stream in group at ts, creating both as needed, and
+// returns the event's ID. Open Live Tail sessions on the group receive it.
+func (f *Fake) Put(group, stream string, ts time.Time, message string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.groups == nil {
+		f.groups = map[string][]types.FilteredLogEvent{}
+	}
+	f.lastID++
+	id := fmt.Sprintf("%056d", f.lastID)
+	ev := types.FilteredLogEvent{
+		EventId:       aws.String(id),
+		LogStreamName: aws.String(stream),
+		Timestamp:     aws.Int64(ts.UnixMilli()),
+		IngestionTime: aws.Int64(time.Now().UnixMilli()),
+		Message:       aws.String(message),
+	}
+	// Events are kept in timestamp order, as FilterLogEvents returns them
+	events := f.groups[group]
+	i := sort.Search(len(events), func(i int) bool { return aws.ToInt64(events[i].Timestamp) > ts.UnixMilli() })
+	f.groups[group] = append(events[:i], append([]types.FilteredLogEvent{ev}, events[i:]...)...)
+
+	for s := range f.sessions {
+		s.offer(group, ev)
+	}
+	return id
+}
+
+// Calls returns how many times FilterLogEvents has been called.
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// EndSessions ends the open Live Tail sessions, with err as the error of
+// their streams, e.g. a *types.SessionTimeoutException.
+func (f *Fake) EndSessions(err error) {
+	f.mu.Lock()
+	sessions := f.sessions
+	f.sessions = nil
+	f.mu.Unlock()
+	for s := range sessions {
+		s.end(err)
+	}
+}
+
+func (f *Fake) FilterLogEvents(_ context.Context, in *cloudwatchlogs.FilterLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+
+	group := aws.ToString(in.LogGroupName)
+	events, ok := f.groups[group]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("The specified log group does not exist: " + group)}
+	}
+	if len(in.LogStreamNames) > 0 && in.LogStreamNamePrefix != nil {
+		return nil, &types.InvalidParameterException{Message: aws.String("logStreamNames and logStreamNamePrefix cannot both be specified")}
+	}
+	match, err := compilePattern(aws.ToString(in.FilterPattern))
+	if err != nil {
+		return nil, err
+	}
+	var matched []types.FilteredLogEvent
+	for _, ev := range events {
+		ts := aws.ToInt64(ev.Timestamp)
+		switch {
+		case in.StartTime != nil && ts < *in.StartTime:
+		case in.EndTime != nil && ts > *in.EndTime:
+		case !streamMatches(aws.ToString(ev.LogStreamName), in.LogStreamNames, aws.ToString(in.LogStreamNamePrefix)):
+		case !match(aws.ToString(ev.Message)):
+		default:
+			matched = append(matched, ev)
+		}
+	}
+
+	pageSize := f.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultFakePageSize
+	}
+	offset := 0
+	if in.NextToken != nil {
+		if offset, err = strconv.Atoi(*in.NextToken); err != nil || offset > len(matched) {
+			return nil, &types.InvalidParameterException{Message: aws.String("invalid nextToken")}
+		}
+	}
+	out := &cloudwatchlogs.FilterLogEventsOutput{Events: matched[offset:min(offset+pageSize, len(matched))]}
+	if offset+pageSize < len(matched) {
+		out.NextToken = aws.String(strconv.Itoa(offset + pageSize))
+	}
+	return out, nil
+}
+
+// StartLiveTail opens a session that receives the events Put from now on.
+// It ends when ctx ends, the stream is closed or EndSessions is called.
+func (f *Fake) StartLiveTail(ctx context.Context, in *cloudwatchlogs.StartLiveTailInput) (cloudwatchlogs.StartLiveTailResponseStreamReader, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(in.LogGroupIdentifiers) == 0 {
+		return nil, &types.InvalidParameterException{Message: aws.String("logGroupIdentifiers is required")}
+	}
+	if (len(in.LogStreamNames) > 0 || len(in.LogStreamNamePrefixes) > 0) && len(in.LogGroupIdentifiers) > 1 {
+		return nil, &types.InvalidParameterException{Message: aws.String("log streams can only be selected in a single log group")}
+	}
+	if len(in.LogStreamNames) > 0 && len(in.LogStreamNamePrefixes) > 0 {
+		return nil, &types.InvalidParameterException{Message: aws.String("logStreamNames and logStreamNamePrefixes cannot both be specified")}
+	}
+	groups := map[string]string{}
+	for _, arn := range in.LogGroupIdentifiers {
+		_, name, ok := strings.Cut(arn, ":log-group:")
+		if !strings.HasPrefix(arn, "arn:") || !ok || strings.HasSuffix(name, "*") {
+			return nil, &types.InvalidParameterException{Message: aws.String("log groups must be given by ARN: " + arn)}
+		}
+		if _, ok := f.groups[name]; !ok {
+			return nil, &types.ResourceNotFoundException{Message: aws.String("The specified log group does not exist: " + arn)}
+		}
+		groups[name] = arn
+	}
+	match, err := compilePattern(aws.ToString(in.LogEventFilterPattern))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fakeSession{
+		groups:   groups,
+		streams:  in.LogStreamNames,
+		prefixes: in.LogStreamNamePrefixes,
+		match:    match,
+		events:   make(chan types.StartLiveTailResponseStream),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	if f.sessions == nil {
+		f.sessions = map[*fakeSession]bool{}
+	}
+	f.sessions[s] = true
+	go s.run(ctx)
+	go func() {
+		<-s.closed
+		f.mu.Lock()
+		delete(f.sessions, s)
+		f.mu.Unlock()
+	}()
+	return s, nil
+}
+
+// fakeSession is a Live Tail session of a Fake. Events offered to it are sent
+// in an update as soon as the reader takes them.
+type fakeSession struct {
+	groups   map[string]string
+	streams  []string
+	prefixes []string
+	match    func(string) bool
+	events   chan types.StartLiveTailResponseStream
+
+	mu      sync.Mutex
+	pending []types.LiveTailSessionLogEvent
+	err     error
+	notify  chan struct{}
+	once    sync.Once
+	done    chan struct{}
+	closed  chan struct{}
+}
+
+func (s *fakeSession) offer(group string, ev types.FilteredLogEvent) {
+	arn, ok := s.groups[group]
+	if !ok || !s.match(aws.ToString(ev.Message)) {
+		return
+	}
+	stream := aws.ToString(ev.LogStreamName)
+	if !streamMatches(stream, s.streams, "") {
+		return
+	}
+	if len(s.prefixes) > 0 && !slices.ContainsFunc(s.prefixes, func(p string) bool { return strings.HasPrefix(stream, p) }) {
+		return
+	}
+	s.mu.Lock()
+	s.pending = append(s.pending, types.LiveTailSessionLogEvent{
+		LogGroupIdentifier: aws.String(arn),
+		LogStreamName:      ev.LogStreamName,
+		Timestamp:          ev.Timestamp,
+		IngestionTime:      ev.IngestionTime,
+		Message:            ev.Message,
+	})
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *fakeSession) run(ctx context.Context) {
+	defer close(s.closed)
+	defer close(s.events)
+	send := func(msg types.StartLiveTailResponseStream) bool {
+		select {
+		case s.events <- msg:
+			return true
+		case <-s.done:
+		case <-ctx.Done():
+		}
+		return false
+	}
+	if !send(&types.StartLiveTailResponseStreamMemberSessionStart{}) {
+		return
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-s.notify:
+		}
+		s.mu.Lock()
+		results := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+		if !send(&types.StartLiveTailResponseStreamMemberSessionUpdate{Value: types.LiveTailSessionUpdate{SessionResults: results}}) {
+			return
+		}
+	}
+}
+
+func (s *fakeSession) end(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+	<-s.closed
+}
+
+func (s *fakeSession) Events() <-chan types.StartLiveTailResponseStream {
+	return s.events
+}
+
+func (s *fakeSession) Close() error {
+	s.end(nil)
+	return nil
+}
+
+func (s *fakeSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func streamMatches(stream string, names []string, prefix string) bool {
+	if len(names) > 0 && !slices.Contains(names, stream) {
+		return false
+	}
+	return strings.HasPrefix(stream, prefix)
+}
+
+var (
+	jsonClause  = regexp.MustCompile(`^\s*\$\.([\w.]+)\s*(!=|=)\s*(.+?)\s*$`)
+	patternTerm = regexp.MustCompile(`-?"[^"]*"|\S+`)
+)
+
+// compilePattern returns a matcher for the subset of filter patterns Fake
+// supports, rejecting the rest as CloudWatch rejects invalid ones.
+func compilePattern(pattern string) (func(string) bool, error) {
+	pattern = strings.TrimSpace(pattern)
+	unsupported := &types.InvalidParameterException{Message: aws.String("filter pattern not supported by the fake: " + pattern)}
+	if inner, ok := strings.CutPrefix(pattern, "{"); ok {
+		inner, ok = strings.CutSuffix(inner, "}")
+		if !ok || strings.Contains(inner, "||") {
+			return nil, unsupported
+		}
+		var clauses []func(map[string]any) bool
+		for _, clause := range strings.Split(inner, "&&") {
+			m := jsonClause.FindStringSubmatch(clause)
+			if m == nil {
+				return nil, unsupported
+			}
+			path, negate, want := strings.Split(m[1], "."), m[2] == "!=", jsonValueMatcher(m[3])
+			clauses = append(clauses, func(doc map[string]any) bool {
+				var v any = doc
+				for _, key := range path {
+					obj, ok := v.(map[string]any)
+					if !ok {
+						return false
+					}
+					if v, ok = obj[key]; !ok {
+						return false
+					}
+				}
+				return want(v) != negate
+			})
+		}
+		return func(message string) bool {
+			var doc map[string]any
+			if json.Unmarshal([]byte(message), &doc) != nil {
+				return false
+			}
+			for _, c := range clauses {
+				if !c(doc) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	}
+
+	var include, exclude []string
+	for _, term := range patternTerm.FindAllString(pattern, -1) {
+		if strings.HasPrefix(term, "?") || strings.HasPrefix(term, "%") || strings.HasPrefix(term, "[") {
+			return nil, unsupported
+		}
+		excluded := strings.HasPrefix(term, "-")
+		term = strings.Trim(strings.TrimPrefix(term, "-"), `"`)
+		if excluded {
+			exclude = append(exclude, term)
+		} else {
+			include = append(include, term)
+		}
+	}
+	return func(message string) bool {
+		for _, term := range include {
+			if !strings.Contains(message, term) {
+				return false
+			}
+		}
+		for _, term := range exclude {
+			if strings.Contains(message, term) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// jsonValueMatcher matches a JSON value against the right-hand side of a
+// comparison: a quoted string, where * is a wildcard, or a bare number or
+// literal.
+func jsonValueMatcher(want string) func(any) bool {
+	if unquoted, err := strconv.Unquote(want); err == nil {
+		parts := strings.Split(unquoted, "*")
+		for i, p := range parts {
+			parts[i] = regexp.QuoteMeta(p)
+		}
+		re := regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+		return func(v any) bool {
+			s, ok := v.(string)
+			return ok && re.MatchString(s)
+		}
+	}
+	return func(v any) bool {
+		if n, ok := v.(float64); ok {
+			f, err := strconv.ParseFloat(want, 64)
+			return err == nil && n == f
+		}
+		raw, _ := json.Marshal(v)
+		return string(raw) == want
+	}
+}
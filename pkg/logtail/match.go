@@ -0,0 +1,53 @@
+package logtail
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Matcher selects the event Tail.WaitFor waits for.
+type Matcher func(Event) bool
+
+// Contains matches events whose message contains s.
+func Contains(s string) Matcher {
+	return func(ev Event) bool { return strings.Contains(ev.Message, s) }
+}
+
+// Regexp matches events whose message matches re.
+func Regexp(re *regexp.Regexp) Matcher {
+	return func(ev Event) bool { return re.MatchString(ev.Message) }
+}
+
+// Field matches the JSON events the bootstrap logs whose top-level key has
+// value, e.g. Field("event", "model_pull_result"). Numbers and booleans are
+// compared in their JSON form.
+func Field(key, value string) Matcher {
+	return func(ev Event) bool {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal([]byte(ev.Message), &fields) != nil {
+			return false
+		}
+		raw, ok := fields[key]
+		if !ok {
+			return false
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s == value
+		}
+		return string(raw) == value
+	}
+}
+
+// All matches events every one of matchers matches.
+func All(matchers ...Matcher) Matcher {
+	return func(ev Event) bool {
+		for _, m := range matchers {
+			if !m(ev) {
+				return false
+			}
+		}
+		return true
+	}
+}
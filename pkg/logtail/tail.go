@@ -0,0 +1,398 @@
+// Package logtail follows a deployment's CloudWatch log groups, such as the
+// streams in the app_log_stream and model_pull_stream outputs, so their events
+// can be read and waited for without SSH access to the instance.
+package logtail
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+const (
+	defaultPollInterval = 2 * time.Second
+	defaultOverlap      = 30 * time.Second
+)
+
+// ErrNoMatch is returned by WaitFor when the tail ends before a matching event.
+var ErrNoMatch = errors.New("logtail: tail ended without a matching event")
+
+// API is the subset of the CloudWatch Logs client used to read events.
+type API interface {
+	FilterLogEvents(ctx context.Context, in *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+}
+
+// LiveTailAPI starts Live Tail sessions. The SDK returns its event stream in
+// an output that cannot be built outside the SDK, so the client is adapted
+// with LiveTail; Fake implements the interface directly.
+type LiveTailAPI interface {
+	StartLiveTail(ctx context.Context, in *cloudwatchlogs.StartLiveTailInput) (cloudwatchlogs.StartLiveTailResponseStreamReader, error)
+}
+
+// LiveTail adapts a CloudWatch Logs client to LiveTailAPI.
+func LiveTail(c *cloudwatchlogs.Client) LiveTailAPI {
+	return liveTailClient{c}
+}
+
+type liveTailClient struct {
+	c *cloudwatchlogs.Client
+}
+
+func (l liveTailClient) StartLiveTail(ctx context.Context, in *cloudwatchlogs.StartLiveTailInput) (cloudwatchlogs.StartLiveTailResponseStreamReader, error) {
+	out, err := l.c.StartLiveTail(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out.GetStream(), nil
+}
+
+// Event is one log event.
+type Event struct {
+	// ID is the CloudWatch event ID. Events from Live Tail sessions have none.
+	ID        string
+	Stream    string
+	Timestamp time.Time
+	Message   string
+	// Token resumes the tail after this event; see Config.Token.
+	Token string
+}
+
+// Config selects the events to tail.
+type Config struct {
+	// Group is the log group name. GroupARN is only needed with Live.
+	Group    string
+	GroupARN string
+	// Streams or StreamPrefix, not both, narrow the tail to some streams of
+	// the group.
+	Streams      []string
+	StreamPrefix string
+	// FilterPattern is a CloudWatch filter pattern, e.g.
+	// { $.event = "model_pull_result" }.
+	FilterPattern string
+	// Start is the time of the oldest event to read; zero reads the group
+	// from its beginning.
+	Start time.Time
+	// Token, taken from the last event handled, resumes a tail in place of
+	// Start. Older events are skipped, as are the events already delivered
+	// from the same millisecond.
+	Token string
+	// Follow keeps tailing after the events logged so far, until the context
+	// ends.
+	Follow bool
+	// Live, when set, follows with Live Tail sessions instead of polling.
+	// Live Tail events have no ID, so they are told apart by stream,
+	// timestamp and message; identical lines logged to one stream in the
+	// same millisecond are delivered once.
+	Live LiveTailAPI
+	// PollInterval is the wait between polls, and between Live Tail
+	// sessions. Default 2s.
+	PollInterval time.Duration
+	// Overlap is how far back each poll reaches before the newest event
+	// delivered, to pick up events CloudWatch ingests late. Default 30s.
+	Overlap time.Duration
+	// Buffer is the capacity of the events channel.
+	Buffer int
+}
+
+// Tail delivers the events of a log group in a channel.
+type Tail struct {
+	events chan Event
+	err    error
+}
+
+// Start tails the events cfg selects until they are all read or, with
+// Follow, until ctx ends.
+func Start(ctx context.Context, api API, cfg Config) (*Tail, error) {
+	if cfg.Group == "" {
+		return nil, errors.New("logtail: no log group")
+	}
+	if len(cfg.Streams) > 0 && cfg.StreamPrefix != "" {
+		return nil, errors.New("logtail: streams and a stream prefix cannot be combined")
+	}
+	if cfg.Live != nil && cfg.Follow && cfg.GroupARN == "" {
+		return nil, fmt.Errorf("logtail: live tail of %s needs the log group ARN", cfg.Group)
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Overlap <= 0 {
+		cfg.Overlap = defaultOverlap
+	}
+
+	t := &Tail{events: make(chan Event, cfg.Buffer)}
+	tl := &tailer{api: api, cfg: cfg, events: t.events, floor: cfg.Start.UnixMilli(), seen: map[string]int64{}}
+	if cfg.Start.IsZero() {
+		tl.floor = 0
+	}
+	if cfg.Token != "" {
+		c, err := decodeToken(cfg.Token)
+		if err != nil {
+			return nil, err
+		}
+		tl.floor, tl.newest, tl.newestKeys = c.Timestamp, c.Timestamp, c.Keys
+		for _, k := range c.Keys {
+			tl.seen[k] = c.Timestamp
+		}
+	}
+
+	go func() {
+		defer close(t.events)
+		t.err = tl.run(ctx)
+	}()
+	return t, nil
+}
+
+// Events returns the events in the order they are read. It is closed when
+// the tail ends.
+func (t *Tail) Events() <-chan Event {
+	return t.events
+}
+
+// Err returns the error that ended the tail, once Events is closed: nil when
+// every event was read, the context's error when it ended a tail.
+func (t *Tail) Err() error {
+	return t.err
+}
+
+// WaitFor returns the first event that match accepts, discarding the events
+// before it.
+func (t *Tail) WaitFor(ctx context.Context, match Matcher) (Event, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Event{}, ctx.Err()
+		case ev, ok := <-t.events:
+			if !ok {
+				if t.err != nil {
+					return Event{}, t.err
+				}
+				return Event{}, ErrNoMatch
+			}
+			if match(ev) {
+				return ev, nil
+			}
+		}
+	}
+}
+
+// cursor is the content of a token.
+type cursor struct {
+	Timestamp int64    `json:"t"`
+	Keys      []string `json:"k"`
+}
+
+func (c cursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeToken(token string) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(raw, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("logtail: invalid token: %w", err)
+	}
+	return c, nil
+}
+
+type tailer struct {
+	api    API
+	cfg    Config
+	events chan<- Event
+	// floor is the timestamp of the oldest event delivered.
+	floor int64
+	// newest is the newest timestamp delivered and newestKeys the keys of
+	// the events delivered at it, which make up the token.
+	newest     int64
+	newestKeys []string
+	// seen maps the keys of the events delivered within the overlap to
+	// their timestamps.
+	seen map[string]int64
+}
+
+func (t *tailer) run(ctx context.Context) error {
+	if !t.cfg.Follow {
+		return t.read(ctx)
+	}
+	for {
+		var err error
+		if t.cfg.Live != nil {
+			err = t.session(ctx)
+		} else {
+			err = t.read(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.cfg.PollInterval):
+		}
+	}
+}
+
+// read delivers every page of events from the overlap before the newest
+// event on.
+func (t *tailer) read(ctx context.Context) error {
+	start := t.prune()
+
+	in := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(t.cfg.Group),
+		StartTime:    aws.Int64(start),
+	}
+	if len(t.cfg.Streams) > 0 {
+		in.LogStreamNames = t.cfg.Streams
+	}
+	if t.cfg.StreamPrefix != "" {
+		in.LogStreamNamePrefix = aws.String(t.cfg.StreamPrefix)
+	}
+	if t.cfg.FilterPattern != "" {
+		in.FilterPattern = aws.String(t.cfg.FilterPattern)
+	}
+	for {
+		out, err := t.api.FilterLogEvents(ctx, in)
+		if err != nil {
+			return fmt.Errorf("filter %s events: %w", t.cfg.Group, err)
+		}
+		for _, ev := range out.Events {
+			err := t.deliver(ctx, Event{
+				ID:        aws.ToString(ev.EventId),
+				Stream:    aws.ToString(ev.LogStreamName),
+				Timestamp: time.UnixMilli(aws.ToInt64(ev.Timestamp)),
+				Message:   aws.ToString(ev.Message),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if out.NextToken == nil {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+// session runs one Live Tail session. Sessions end after three hours at
+// most; the events logged between two sessions are caught up on by reading
+// them once the next one has started.
+func (t *tailer) session(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := &cloudwatchlogs.StartLiveTailInput{LogGroupIdentifiers: []string{t.cfg.GroupARN}}
+	if len(t.cfg.Streams) > 0 {
+		in.LogStreamNames = t.cfg.Streams
+	}
+	if t.cfg.StreamPrefix != "" {
+		in.LogStreamNamePrefixes = []string{t.cfg.StreamPrefix}
+	}
+	if t.cfg.FilterPattern != "" {
+		in.LogEventFilterPattern = aws.String(t.cfg.FilterPattern)
+	}
+	stream, err := t.cfg.Live.StartLiveTail(ctx, in)
+	if err != nil {
+		return fmt.Errorf("start live tail of %s: %w", t.cfg.Group, err)
+	}
+	defer stream.Close()
+
+	if err := t.read(ctx); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-stream.Events():
+			if !ok {
+				var timeout *types.SessionTimeoutException
+				if err := stream.Err(); err != nil && !errors.As(err, &timeout) {
+					return fmt.Errorf("live tail of %s: %w", t.cfg.Group, err)
+				}
+				return nil
+			}
+			update, ok := msg.(*types.StartLiveTailResponseStreamMemberSessionUpdate)
+			if !ok {
+				continue
+			}
+			t.prune()
+			for _, ev := range update.Value.SessionResults {
+				err := t.deliver(ctx, Event{
+					Stream:    aws.ToString(ev.LogStreamName),
+					Timestamp: time.UnixMilli(aws.ToInt64(ev.Timestamp)),
+					Message:   aws.ToString(ev.Message),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// prune forgets the events delivered before the overlap, which are not
+// expected again, and returns the start of the overlap.
+func (t *tailer) prune() int64 {
+	start := max(t.floor, t.newest-t.cfg.Overlap.Milliseconds())
+	for k, ts := range t.seen {
+		if ts < start {
+			delete(t.seen, k)
+		}
+	}
+	return start
+}
+
+// deliver sends ev unless it is older than the floor or was delivered
+// before.
+func (t *tailer) deliver(ctx context.Context, ev Event) error {
+	ts := ev.Timestamp.UnixMilli()
+	if ts < t.floor {
+		return nil
+	}
+	// Events read while live tailing also go by their content, which is all
+	// that identifies the same event in a session.
+	var keys []string
+	if ev.ID != "" {
+		keys = append(keys, ev.ID)
+	}
+	if ev.ID == "" || t.cfg.Live != nil {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s\x00%d\x00%s", ev.Stream, ts, ev.Message)
+		keys = append(keys, "~"+strconv.FormatUint(h.Sum64(), 36))
+	}
+	for _, k := range keys {
+		if _, ok := t.seen[k]; ok {
+			return nil
+		}
+	}
+	for _, k := range keys {
+		t.seen[k] = ts
+	}
+
+	switch {
+	case ts > t.newest:
+		t.newest, t.newestKeys = ts, keys
+	case ts == t.newest:
+		t.newestKeys = append(t.newestKeys, keys...)
+	}
+	ev.Token = cursor{Timestamp: t.newest, Keys: t.newestKeys}.encode()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case t.events <- ev:
+		return nil
+	}
+}
@@ -0,0 +1,289 @@
+package logtail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	group       = "/ds-0a1b2c3d/logs"
+	groupARN    = "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/logs"
+	appStream   = "ds-0a1b2c3d-stream"
+	otherStream = "ds-0a1b2c3d-stream-i-0d1e2a3d4b5c6e7f8"
+)
+
+var base = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// event returns a log line as the bootstrap logs it.
+func event(stage, level, message string) string {
+	return fmt.Sprintf(`{"timestamp":"2026-10-15T12:00:00.000Z","deployment_id":"ds-0a1b2c3d","stage":%q,"level":%q,"message":%q}`, stage, level, message)
+}
+
+// collect returns the messages of every event until the tail ends.
+func collect(t *testing.T, tail *Tail) []string {
+	t.Helper()
+	var messages []string
+	for ev := range tail.Events() {
+		messages = append(messages, ev.Message)
+	}
+	require.NoError(t, tail.Err())
+	return messages
+}
+
+// next returns the next event, failing after a few seconds.
+func next(t *testing.T, tail *Tail) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-tail.Events():
+		require.True(t, ok, "tail ended: %v", tail.Err())
+		return ev
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no event")
+		return Event{}
+	}
+}
+
+func TestReadEveryPage(t *testing.T) {
+	fake := &Fake{PageSize: 2}
+	fake.Put(group, appStream, base.Add(-2*time.Hour), "too old")
+	for i := range 4 {
+		fake.Put(group, appStream, base.Add(time.Duration(i)*time.Second), fmt.Sprintf("line %d", i))
+	}
+	fake.Put(group, otherStream, base, "other stream")
+
+	tail, err := Start(context.Background(), fake, Config{Group: group, Streams: []string{appStream}, Start: base.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 0", "line 1", "line 2", "line 3"}, collect(t, tail))
+	assert.Equal(t, 2, fake.Calls())
+
+	tail, err = Start(context.Background(), fake, Config{Group: group, StreamPrefix: appStream + "-"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other stream"}, collect(t, tail))
+
+	tail, err = Start(context.Background(), fake, Config{Group: "/ds-0a1b2c3d/missing"})
+	require.NoError(t, err)
+	for range tail.Events() {
+	}
+	var notFound *types.ResourceNotFoundException
+	assert.ErrorAs(t, tail.Err(), &notFound)
+}
+
+func TestFollow(t *testing.T) {
+	fake := &Fake{PageSize: 2}
+	fake.Put(group, appStream, base, event("runner", "info", "Stage docker completed"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tail, err := Start(ctx, fake, Config{Group: group, Follow: true, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	assert.Contains(t, next(t, tail).Message, "Stage docker completed")
+
+	// Late events are picked up within the overlap, once each
+	fake.Put(group, appStream, base, event("runner", "info", "Stage app attempt 1 of 3"))
+	fake.Put(group, appStream, base.Add(-time.Second), event("app", "error", "Docker compose attempt 1 failed"))
+	fake.Put(group, appStream, base.Add(time.Second), event("runner", "info", "Stage app completed"))
+	var messages []string
+	for range 3 {
+		messages = append(messages, next(t, tail).Message)
+	}
+	assert.ElementsMatch(t, []string{
+		event("runner", "info", "Stage app attempt 1 of 3"),
+		event("app", "error", "Docker compose attempt 1 failed"),
+		event("runner", "info", "Stage app completed"),
+	}, messages)
+
+	calls := fake.Calls()
+	require.Eventually(t, func() bool { return fake.Calls() > calls+3 }, 5*time.Second, time.Millisecond)
+	cancel()
+	for ev := range tail.Events() {
+		assert.Fail(t, "event delivered twice", ev.Message)
+	}
+	assert.ErrorIs(t, tail.Err(), context.Canceled)
+}
+
+func TestResumeFromToken(t *testing.T) {
+	fake := &Fake{}
+	fake.Put(group, appStream, base, "first")
+	fake.Put(group, appStream, base, "second")
+	fake.Put(group, appStream, base.Add(time.Second), "third")
+
+	tail, err := Start(context.Background(), fake, Config{Group: group})
+	require.NoError(t, err)
+	var events []Event
+	for ev := range tail.Events() {
+		events = append(events, ev)
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, base, events[0].Timestamp.UTC())
+	assert.Equal(t, appStream, events[0].Stream)
+	assert.NotEmpty(t, events[0].ID)
+
+	// Resuming after the first event skips it but not the second, logged in
+	// the same millisecond
+	tail, err = Start(context.Background(), fake, Config{Group: group, Token: events[0].Token})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second", "third"}, collect(t, tail))
+
+	fake.Put(group, appStream, base.Add(time.Second), "fourth")
+	fake.Put(group, appStream, base.Add(-time.Second), "late, before the token")
+	tail, err = Start(context.Background(), fake, Config{Group: group, Token: events[2].Token})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fourth"}, collect(t, tail))
+
+	_, err = Start(context.Background(), fake, Config{Group: group, Token: "not a token"})
+	assert.ErrorContains(t, err, "invalid token")
+}
+
+func TestWaitFor(t *testing.T) {
+	fake := &Fake{}
+	fake.Put(group, appStream, base, event("models", "info", "Pulling qwen2.5:0.5b"))
+	fake.Put(group, appStream, base.Add(time.Second),
+		`{"level":"info","event":"model_pull_result","status":"ready","model":"qwen2.5:0.5b","pull_seconds":42}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tail, err := Start(ctx, fake, Config{Group: group, Follow: true, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	ev, err := tail.WaitFor(ctx, All(Field("event", "model_pull_result"), Field("pull_seconds", "42")))
+	require.NoError(t, err)
+	assert.Contains(t, ev.Message, `"status":"ready"`)
+
+	go fake.Put(group, appStream, base.Add(2*time.Second), event("runner", "info", "Stage models completed"))
+	ev, err = tail.WaitFor(ctx, Regexp(regexp.MustCompile(`Stage \w+ completed`)))
+	require.NoError(t, err)
+	assert.Equal(t, base.Add(2*time.Second), ev.Timestamp.UTC())
+
+	tail, err = Start(ctx, fake, Config{Group: group})
+	require.NoError(t, err)
+	_, err = tail.WaitFor(ctx, Contains("Failed to pull model"))
+	assert.ErrorIs(t, err, ErrNoMatch)
+
+	waitCtx, cancelWait := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelWait()
+	tail, err = Start(ctx, fake, Config{Group: group, Follow: true, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	_, err = tail.WaitFor(waitCtx, Contains("Failed to pull model"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLiveTail(t *testing.T) {
+	fake := &Fake{}
+	fake.Put(group, appStream, base, event("runner", "info", "Stage docker completed"))
+	fake.Put(group, otherStream, base, "other stream")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tail, err := Start(ctx, fake, Config{
+		Group: group, GroupARN: groupARN, Streams: []string{appStream},
+		Follow: true, Live: fake, PollInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	ev := next(t, tail)
+	assert.NotEmpty(t, ev.ID, "history is read with FilterLogEvents")
+	assert.Contains(t, ev.Message, "Stage docker completed")
+
+	calls := fake.Calls()
+	fake.Put(group, appStream, base.Add(time.Second), event("runner", "info", "Stage app completed"))
+	fake.Put(group, otherStream, base.Add(time.Second), "other stream")
+	ev = next(t, tail)
+	assert.Empty(t, ev.ID, "new events come from the session")
+	assert.Contains(t, ev.Message, "Stage app completed")
+	assert.Equal(t, calls, fake.Calls(), "a session needs no polling")
+
+	// Events logged while no session is open are caught up on once the next
+	// session starts, and the last event of the old one is not repeated
+	fake.EndSessions(&types.SessionTimeoutException{Message: aws.String("Session timed out")})
+	fake.Put(group, appStream, base.Add(2*time.Second), event("runner", "info", "Stage models completed"))
+	ev = next(t, tail)
+	assert.Contains(t, ev.Message, "Stage models completed")
+	select {
+	case ev := <-tail.Events():
+		assert.Fail(t, "unexpected event", ev.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.EndSessions(errors.New("connection reset"))
+	for range tail.Events() {
+	}
+	assert.ErrorContains(t, tail.Err(), "live tail of /ds-0a1b2c3d/logs: connection reset")
+}
+
+func TestStartInvalidConfig(t *testing.T) {
+	fake := &Fake{}
+	cases := map[string]struct {
+		cfg  Config
+		want string
+	}{
+		"no group":            {Config{}, "no log group"},
+		"streams and prefix":  {Config{Group: group, Streams: []string{appStream}, StreamPrefix: appStream}, "cannot be combined"},
+		"live without an ARN": {Config{Group: group, Follow: true, Live: fake}, "needs the log group ARN"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Start(context.Background(), fake, tc.cfg)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestFakeFilterPatterns(t *testing.T) {
+	fake := &Fake{}
+	messages := []string{
+		event("app", "error", "Docker compose attempt 2 failed"),
+		event("models", "error", "Failed to pull model after 3 attempts"),
+		`{"level":"info","event":"model_pull_result","status":"ready","pull_seconds":42,"details":{"source":"snapshot"}}`,
+		"Cloud-init v. 24.1 finished",
+	}
+	for i, m := range messages {
+		fake.Put(group, appStream, base.Add(time.Duration(i)*time.Second), m)
+	}
+
+	cases := map[string][]int{
+		"":                         {0, 1, 2, 3},
+		"finished":                 {3},
+		`"compose attempt" failed`: {0},
+		`error -Docker`:            {1},
+		`{ $.level = "error" }`:    {0, 1},
+		`{ $.level = "error" && $.message = "Docker compose attempt *" }`: {0},
+		`{ $.stage != "app" }`:           {1},
+		`{ $.pull_seconds = 42 }`:        {2},
+		`{ $.details.source = "snap*" }`: {2},
+	}
+	for pattern, want := range cases {
+		t.Run(pattern, func(t *testing.T) {
+			out, err := fake.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{
+				LogGroupName:  aws.String(group),
+				FilterPattern: aws.String(pattern),
+			})
+			require.NoError(t, err)
+			var got []int
+			for _, ev := range out.Events {
+				for i, m := range messages {
+					if aws.ToString(ev.Message) == m {
+						got = append(got, i)
+					}
+				}
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+
+	for _, pattern := range []string{`{ $.level = "error" || $.level = "warn" }`, `{ $.duration_ms > 100 }`, `?error ?warn`} {
+		_, err := fake.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName:  aws.String(group),
+			FilterPattern: aws.String(pattern),
+		})
+		var invalid *types.InvalidParameterException
+		assert.ErrorAs(t, err, &invalid, pattern)
+	}
+}
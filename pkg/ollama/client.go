@@ -0,0 +1,428 @@
+// Package ollama is a small client for the subset of the Ollama HTTP API used
+// to verify and operate a deployment: listing, pulling, deleting and running
+// models.
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout      = 2 * time.Minute
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 2 * time.Second
+)
+
+// Client talks to a single Ollama endpoint such as the module's ollama_api_url output.
+type Client struct {
+	baseURL      *url.URL
+	httpClient   *http.Client
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	authHeader   string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the underlying HTTP client, e.g. to trust the
+// self-signed certificate served by the API proxy.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout bounds each non-streaming request. Pulls are only bounded by
+// the caller's context since large models take hours.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithRetries sets how many times a failed request is retried and the
+// initial backoff, which doubles after every attempt.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+		c.retryBackoff = backoff
+	}
+}
+
+// WithBearerToken authenticates against an API proxy in bearer mode.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.authHeader = "Bearer " + token }
+}
+
+// WithBasicAuth authenticates against an API proxy in basic mode.
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) {
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(user, password)
+		c.authHeader = req.Header.Get("Authorization")
+	}
+}
+
+// NewClient returns a Client for the Ollama API at baseURL.
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse ollama url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("ollama url %q must use http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:      u,
+		httpClient:   http.DefaultClient,
+		timeout:      defaultTimeout,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// APIError is returned when Ollama answers with a non-2xx status or reports
+// an error inside a streamed response.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return "ollama: " + e.Message
+	}
+	return fmt.Sprintf("ollama: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// permanentError marks a failure that must not be retried, such as an error
+// returned by a caller's progress callback.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryable reports whether a request that failed with err should be retried.
+// Transport errors, including a per-attempt timeout, are always retryable;
+// the caller's own context is checked separately.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// do sends a JSON request and hands the successful response body to handle,
+// retrying transport errors, 429s and 5xx responses with exponential backoff.
+func (c *Client) do(ctx context.Context, method, path string, in any, bounded bool, handle func(io.Reader) error) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encode %s request: %w", path, err)
+		}
+	}
+
+	backoff := c.retryBackoff
+	var err error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %w (last error: %v)", path, ctx.Err(), err)
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		err = c.attempt(ctx, method, path, payload, bounded, handle)
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%s failed after %d attempts: %w", path, c.maxRetries+1, err)
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, bounded bool, handle func(io.Reader) error) error {
+	if bounded && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.authHeader != "" {
+		req.Header.Set("Authorization", c.authHeader)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	return handle(resp.Body)
+}
+
+func decodeError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Error string `json:"error"`
+	}
+	msg := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+		msg = body.Error
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: msg}
+}
+
+func decodeJSON(out any) func(io.Reader) error {
+	return func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(out)
+	}
+}
+
+// Version returns the version reported by the Ollama server.
+func (c *Client) Version(ctx context.Context) (string, error) {
+	var out struct {
+		Version string `json:"version"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/version", nil, true, decodeJSON(&out)); err != nil {
+		return "", err
+	}
+	return out.Version, nil
+}
+
+// Model is an entry of the /api/tags listing.
+type Model struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+// ModelDetails describes the format and size class of a model.
+type ModelDetails struct {
+	Format            string `json:"format"`
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// ListModels returns the models available locally on the Ollama host.
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	var out struct {
+		Models []Model `json:"models"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/tags", nil, true, decodeJSON(&out)); err != nil {
+		return nil, err
+	}
+	return out.Models, nil
+}
+
+// HasModel reports whether name is listed by /api/tags. A name without a tag
+// matches the ":latest" tag, as it does for the ollama CLI.
+func (c *Client) HasModel(ctx context.Context, name string) (bool, error) {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return false, err
+	}
+	want := normalizeName(name)
+	for _, m := range models {
+		if normalizeName(m.Name) == want || normalizeName(m.Model) == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func normalizeName(name string) string {
+	if name != "" && !strings.Contains(name, ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+// PullProgress is one status line of a streamed /api/pull response.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Percent returns how much of the current layer has been downloaded, or -1
+// when the status line carries no size information.
+func (p PullProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Completed) / float64(p.Total) * 100
+}
+
+// Pull downloads model on the Ollama host, calling fn for every progress
+// update. Returning an error from fn aborts the pull. Interrupted pulls are
+// retried and resume where the registry download stopped.
+func (c *Client) Pull(ctx context.Context, model string, fn func(PullProgress) error) error {
+	req := map[string]any{"model": model, "stream": true}
+	return c.do(ctx, http.MethodPost, "/api/pull", req, false, func(r io.Reader) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64<<10), 1<<20)
+		var last PullProgress
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var p PullProgress
+			if err := json.Unmarshal(line, &p); err != nil {
+				return fmt.Errorf("decode pull progress: %w", err)
+			}
+			if p.Error != "" {
+				return &APIError{Message: p.Error}
+			}
+			if fn != nil {
+				if err := fn(p); err != nil {
+					return &permanentError{err}
+				}
+			}
+			last = p
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		if last.Status != "success" {
+			return fmt.Errorf("pull %s: stream ended with status %q", model, last.Status)
+		}
+		return nil
+	})
+}
+
+// Delete removes model from the Ollama host. Deleting a model that is not
+// there fails with a 404 APIError.
+func (c *Client) Delete(ctx context.Context, model string) error {
+	req := map[string]any{"model": model}
+	return c.do(ctx, http.MethodDelete, "/api/delete", req, true, func(io.Reader) error { return nil })
+}
+
+// Options are model runtime parameters accepted by generate and chat.
+type Options struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+}
+
+// GenerateRequest is the body of /api/generate. Streaming is always disabled.
+type GenerateRequest struct {
+	Model     string   `json:"model"`
+	Prompt    string   `json:"prompt"`
+	System    string   `json:"system,omitempty"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+	Options   *Options `json:"options,omitempty"`
+}
+
+// GenerateResponse is the final, non-streamed /api/generate response.
+type GenerateResponse struct {
+	Model              string        `json:"model"`
+	CreatedAt          time.Time     `json:"created_at"`
+	Response           string        `json:"response"`
+	Done               bool          `json:"done"`
+	DoneReason         string        `json:"done_reason"`
+	TotalDuration      time.Duration `json:"total_duration"`
+	LoadDuration       time.Duration `json:"load_duration"`
+	PromptEvalCount    int           `json:"prompt_eval_count"`
+	EvalCount          int           `json:"eval_count"`
+	EvalDuration       time.Duration `json:"eval_duration"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration"`
+}
+
+// Generate runs a single completion.
+func (c *Client) Generate(ctx context.Context, in GenerateRequest) (*GenerateResponse, error) {
+	body := struct {
+		GenerateRequest
+		Stream bool `json:"stream"`
+	}{GenerateRequest: in}
+
+	var out GenerateResponse
+	if err := c.do(ctx, http.MethodPost, "/api/generate", body, true, decodeJSON(&out)); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Message is a single chat turn.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is the body of /api/chat. Streaming is always disabled.
+type ChatRequest struct {
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	KeepAlive string    `json:"keep_alive,omitempty"`
+	Options   *Options  `json:"options,omitempty"`
+}
+
+// ChatResponse is the final, non-streamed /api/chat response.
+type ChatResponse struct {
+	Model         string        `json:"model"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Message       Message       `json:"message"`
+	Done          bool          `json:"done"`
+	DoneReason    string        `json:"done_reason"`
+	TotalDuration time.Duration `json:"total_duration"`
+	EvalCount     int           `json:"eval_count"`
+}
+
+// Chat sends a conversation and returns the assistant's reply.
+func (c *Client) Chat(ctx context.Context, in ChatRequest) (*ChatResponse, error) {
+	body := struct {
+		ChatRequest
+		Stream bool `json:"stream"`
+	}{ChatRequest: in}
+
+	var out ChatResponse
+	if err := c.do(ctx, http.MethodPost, "/api/chat", body, true, decodeJSON(&out)); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
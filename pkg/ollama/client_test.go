@@ -0,0 +1,295 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOllama serves a minimal imitation of the Ollama API.
+type fakeOllama struct {
+	models       []string
+	failRequests int32 // number of leading requests answered with 503
+	requests     int32
+	authHeader   string
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddInt32(&f.requests, 1)
+	if n <= f.failRequests {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":"loading"}`)
+		return
+	}
+	if f.authHeader != "" && r.Header.Get("Authorization") != f.authHeader {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/version":
+		fmt.Fprint(w, `{"version":"0.5.7"}`)
+
+	case "/api/tags":
+		var out struct {
+			Models []Model `json:"models"`
+		}
+		for _, m := range f.models {
+			out.Models = append(out.Models, Model{Name: m, Model: m})
+		}
+		json.NewEncoder(w).Encode(out)
+
+	case "/api/pull":
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing:latest" {
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"pulling abc","digest":"sha256:abc","total":200,"completed":50}`)
+		fmt.Fprintln(w, `{"status":"pulling abc","digest":"sha256:abc","total":200,"completed":200}`)
+		fmt.Fprintln(w, `{"status":"verifying sha256 digest"}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+		f.models = append(f.models, req.Model)
+
+	case "/api/delete":
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for i, m := range f.models {
+			if m == req.Model {
+				f.models = append(f.models[:i], f.models[i+1:]...)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":"model '%s' not found"}`, req.Model)
+
+	case "/api/generate":
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != false {
+			http.Error(w, `{"error":"expected stream=false"}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"model":%q,"response":"pong","done":true,"done_reason":"stop","eval_count":1,"total_duration":1500000}`, req["model"])
+
+	case "/api/chat":
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		last := req.Messages[len(req.Messages)-1].Content
+		fmt.Fprintf(w, `{"model":%q,"message":{"role":"assistant","content":"echo: %s"},"done":true}`, req.Model, last)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestClient(t *testing.T, f *fakeOllama, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	opts = append([]Option{WithRetries(3, time.Millisecond)}, opts...)
+	c, err := NewClient(srv.URL+"/", opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNewClientRejectsBadURL(t *testing.T) {
+	_, err := NewClient("ftp://example.com")
+	assert.Error(t, err)
+}
+
+func TestListModelsAndHasModel(t *testing.T) {
+	c := newTestClient(t, &fakeOllama{models: []string{"deepseek-r1:671b", "llama3:latest"}})
+	ctx := context.Background()
+
+	models, err := c.ListModels(ctx)
+	require.NoError(t, err)
+	assert.Len(t, models, 2)
+
+	ok, err := c.HasModel(ctx, "deepseek-r1:671b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.HasModel(ctx, "llama3")
+	require.NoError(t, err)
+	assert.True(t, ok, "untagged name should match :latest")
+
+	ok, err = c.HasModel(ctx, "deepseek-r1:7b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVersion(t *testing.T) {
+	c := newTestClient(t, &fakeOllama{})
+
+	version, err := c.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0.5.7", version)
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	f := &fakeOllama{models: []string{"m:1"}, failRequests: 2}
+	c := newTestClient(t, f)
+
+	models, err := c.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, models, 1)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&f.requests))
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	f := &fakeOllama{failRequests: 100}
+	c := newTestClient(t, f, WithRetries(1, time.Millisecond))
+
+	_, err := c.ListModels(context.Background())
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, "loading", apiErr.Message)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&f.requests))
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	f := &fakeOllama{authHeader: "Bearer secret"}
+	c := newTestClient(t, f)
+
+	_, err := c.ListModels(context.Background())
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&f.requests))
+}
+
+func TestAuthHeaders(t *testing.T) {
+	c := newTestClient(t, &fakeOllama{authHeader: "Bearer secret"}, WithBearerToken("secret"))
+	_, err := c.ListModels(context.Background())
+	assert.NoError(t, err)
+
+	// "ollama:secret" base64-encoded
+	c = newTestClient(t, &fakeOllama{authHeader: "Basic b2xsYW1hOnNlY3JldA=="}, WithBasicAuth("ollama", "secret"))
+	_, err = c.ListModels(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestPullStreamsProgress(t *testing.T) {
+	f := &fakeOllama{}
+	c := newTestClient(t, f)
+	ctx := context.Background()
+
+	var statuses []string
+	var percents []float64
+	err := c.Pull(ctx, "tiny:latest", func(p PullProgress) error {
+		statuses = append(statuses, p.Status)
+		if pct := p.Percent(); pct >= 0 {
+			percents = append(percents, pct)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "success", statuses[len(statuses)-1])
+	assert.Equal(t, []float64{25, 100}, percents)
+
+	ok, err := c.HasModel(ctx, "tiny")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestPullStreamError(t *testing.T) {
+	c := newTestClient(t, &fakeOllama{})
+
+	err := c.Pull(context.Background(), "missing:latest", nil)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Contains(t, apiErr.Message, "file does not exist")
+}
+
+func TestPullCallbackErrorAborts(t *testing.T) {
+	f := &fakeOllama{}
+	c := newTestClient(t, f)
+	stop := errors.New("stop")
+
+	err := c.Pull(context.Background(), "tiny:latest", func(PullProgress) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&f.requests), "callback errors must not be retried")
+}
+
+func TestDelete(t *testing.T) {
+	f := &fakeOllama{models: []string{"tiny:latest", "llama3:latest"}}
+	c := newTestClient(t, f)
+	ctx := context.Background()
+
+	require.NoError(t, c.Delete(ctx, "tiny:latest"))
+	ok, err := c.HasModel(ctx, "tiny")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = c.Delete(ctx, "tiny:latest")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestGenerate(t *testing.T) {
+	c := newTestClient(t, &fakeOllama{})
+
+	resp, err := c.Generate(context.Background(), GenerateRequest{
+		Model:   "tiny:latest",
+		Prompt:  "ping",
+		Options: &Options{NumPredict: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pong", resp.Response)
+	assert.True(t, resp.Done)
+	assert.Equal(t, 1500*time.Microsecond, resp.TotalDuration)
+}
+
+func TestOptionsKeepZeroSeed(t *testing.T) {
+	body, err := json.Marshal(Options{Seed: new(int)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"seed":0}`, string(body))
+}
+
+func TestChat(t *testing.T) {
+	c := newTestClient(t, &fakeOllama{})
+
+	resp, err := c.Chat(context.Background(), ChatRequest{
+		Model:    "tiny:latest",
+		Messages: []Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "assistant", resp.Message.Role)
+	assert.Equal(t, "echo: hello", resp.Message.Content)
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(srv.URL, WithTimeout(20*time.Millisecond), WithRetries(0, 0))
+	require.NoError(t, err)
+
+	_, err = c.ListModels(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,257 @@
+// Package openwebui checks that a deployed OpenWebUI instance is up, can be
+// signed into and sees the models served by its Ollama backend.
+package openwebui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Client talks to a single OpenWebUI instance such as the module's openwebui_url output.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	timeout    time.Duration
+	token      string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the underlying HTTP client.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout bounds each request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithToken uses an existing session or API key instead of signing in.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// NewClient returns a Client for the OpenWebUI instance at baseURL.
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse openwebui url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("openwebui url %q must use http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		timeout:    defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// APIError is returned when OpenWebUI answers with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("openwebui: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode %s request: %w", path, err)
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// decodeError extracts FastAPI's {"detail": ...} message when present.
+func decodeError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Detail any `json:"detail"`
+	}
+	msg := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &body) == nil && body.Detail != nil {
+		if s, ok := body.Detail.(string); ok {
+			msg = s
+		}
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: msg}
+}
+
+// Healthy returns nil when the /health endpoint reports the app is up.
+func (c *Client) Healthy(ctx context.Context) error {
+	var out struct {
+		Status bool `json:"status"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/health", nil, &out); err != nil {
+		return err
+	}
+	if !out.Status {
+		return errors.New("openwebui: health endpoint reported status false")
+	}
+	return nil
+}
+
+// WaitHealthy polls Healthy every interval until it succeeds or ctx is done.
+func (c *Client) WaitHealthy(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Healthy(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for openwebui: %w (last error: %v)", ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Credentials identify an OpenWebUI user.
+type Credentials struct {
+	Name     string
+	Email    string
+	Password string
+}
+
+// Session is the signed-in user returned by the auth endpoints.
+type Session struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Token string `json:"token"`
+}
+
+// SignIn authenticates an existing user and uses its token for later calls.
+func (c *Client) SignIn(ctx context.Context, creds Credentials) (*Session, error) {
+	in := map[string]string{"email": creds.Email, "password": creds.Password}
+	return c.authenticate(ctx, "/api/v1/auths/signin", in)
+}
+
+// SignUp registers a new user and uses its token for later calls. On a fresh
+// instance the first user to sign up becomes the admin.
+func (c *Client) SignUp(ctx context.Context, creds Credentials) (*Session, error) {
+	in := map[string]string{"name": creds.Name, "email": creds.Email, "password": creds.Password}
+	return c.authenticate(ctx, "/api/v1/auths/signup", in)
+}
+
+func (c *Client) authenticate(ctx context.Context, path string, in any) (*Session, error) {
+	var s Session
+	if err := c.do(ctx, http.MethodPost, path, in, &s); err != nil {
+		return nil, err
+	}
+	if s.Token == "" {
+		return nil, fmt.Errorf("openwebui: %s returned no token", path)
+	}
+	c.token = s.Token
+	return &s, nil
+}
+
+// EnsureAdmin signs in as creds, signing up first if the user does not exist
+// yet, and fails unless the resulting session has the admin role.
+func (c *Client) EnsureAdmin(ctx context.Context, creds Credentials) (*Session, error) {
+	s, err := c.SignIn(ctx, creds)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnauthorized) {
+		s, err = c.SignUp(ctx, creds)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.Role != "admin" {
+		return nil, fmt.Errorf("openwebui: user %s has role %q, want admin", s.Email, s.Role)
+	}
+	return s, nil
+}
+
+// Model is an entry of the /api/models listing.
+type Model struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// Models lists the models OpenWebUI can route to. It requires a session.
+func (c *Client) Models(ctx context.Context) ([]Model, error) {
+	var out struct {
+		Data []Model `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/models", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// HasOllamaModel reports whether the Ollama backend's model name is listed.
+func (c *Client) HasOllamaModel(ctx context.Context, name string) (bool, error) {
+	models, err := c.Models(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	for _, m := range models {
+		if m.OwnedBy == "ollama" && m.ID == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
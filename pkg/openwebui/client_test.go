@@ -0,0 +1,190 @@
+package openwebui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOpenWebUI imitates the handful of OpenWebUI endpoints the client uses.
+type stubOpenWebUI struct {
+	mu           sync.Mutex
+	unhealthyFor int // number of /health calls answered with 503
+	users        map[string]stubUser
+	ollamaModels []string
+}
+
+type stubUser struct {
+	password string
+	role     string
+}
+
+func newStub() *stubOpenWebUI {
+	return &stubOpenWebUI{users: map[string]stubUser{}}
+}
+
+func (s *stubOpenWebUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	detail := func(code int, msg string) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]string{"detail": msg})
+	}
+
+	switch r.URL.Path {
+	case "/health":
+		if s.unhealthyFor > 0 {
+			s.unhealthyFor--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"status":true}`)
+
+	case "/api/v1/auths/signin", "/api/v1/auths/signup":
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		user, exists := s.users[in["email"]]
+		if r.URL.Path == "/api/v1/auths/signup" {
+			if exists {
+				detail(http.StatusBadRequest, "Uh-oh! This email is already registered.")
+				return
+			}
+			user = stubUser{password: in["password"], role: "user"}
+			if len(s.users) == 0 {
+				user.role = "admin"
+			}
+			s.users[in["email"]] = user
+		} else if !exists || user.password != in["password"] {
+			detail(http.StatusBadRequest, "The email or password provided is incorrect.")
+			return
+		}
+		json.NewEncoder(w).Encode(Session{Email: in["email"], Role: user.role, Token: "token-" + in["email"]})
+
+	case "/api/models":
+		if r.Header.Get("Authorization") == "" {
+			detail(http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+		var out struct {
+			Data []Model `json:"data"`
+		}
+		for _, m := range s.ollamaModels {
+			out.Data = append(out.Data, Model{ID: m, Name: m, OwnedBy: "ollama"})
+		}
+		out.Data = append(out.Data, Model{ID: "gpt-4o", Name: "gpt-4o", OwnedBy: "openai"})
+		json.NewEncoder(w).Encode(out)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestClient(t *testing.T, stub *stubOpenWebUI) *Client {
+	t.Helper()
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(srv.URL)
+	require.NoError(t, err)
+	return c
+}
+
+var admin = Credentials{Name: "Admin", Email: "admin@example.com", Password: "s3cret"}
+
+func TestWaitHealthy(t *testing.T) {
+	stub := newStub()
+	stub.unhealthyFor = 3
+	c := newTestClient(t, stub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, c.WaitHealthy(ctx, 5*time.Millisecond))
+	assert.Zero(t, stub.unhealthyFor)
+}
+
+func TestWaitHealthyGivesUp(t *testing.T) {
+	stub := newStub()
+	stub.unhealthyFor = 1000
+	c := newTestClient(t, stub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.WaitHealthy(ctx, 5*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestEnsureAdminSignsUpOnFreshInstance(t *testing.T) {
+	stub := newStub()
+	c := newTestClient(t, stub)
+
+	s, err := c.EnsureAdmin(context.Background(), admin)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", s.Role)
+	assert.Contains(t, stub.users, admin.Email)
+}
+
+func TestEnsureAdminSignsInExistingUser(t *testing.T) {
+	stub := newStub()
+	stub.users[admin.Email] = stubUser{password: admin.Password, role: "admin"}
+	c := newTestClient(t, stub)
+
+	s, err := c.EnsureAdmin(context.Background(), admin)
+	require.NoError(t, err)
+	assert.Equal(t, "token-"+admin.Email, s.Token)
+}
+
+func TestEnsureAdminRejectsNonAdmin(t *testing.T) {
+	stub := newStub()
+	stub.users["first@example.com"] = stubUser{password: "x", role: "admin"}
+	c := newTestClient(t, stub)
+
+	_, err := c.EnsureAdmin(context.Background(), admin)
+	assert.ErrorContains(t, err, `role "user"`)
+}
+
+func TestEnsureAdminWrongPassword(t *testing.T) {
+	stub := newStub()
+	stub.users[admin.Email] = stubUser{password: "other", role: "admin"}
+	c := newTestClient(t, stub)
+
+	_, err := c.EnsureAdmin(context.Background(), admin)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "already registered")
+}
+
+func TestHasOllamaModel(t *testing.T) {
+	stub := newStub()
+	stub.ollamaModels = []string{"deepseek-r1:671b", "llama3:latest"}
+	c := newTestClient(t, stub)
+	ctx := context.Background()
+
+	_, err := c.Models(ctx)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr, "models require a session")
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	_, err = c.EnsureAdmin(ctx, admin)
+	require.NoError(t, err)
+
+	for name, want := range map[string]bool{
+		"deepseek-r1:671b": true,
+		"llama3":           true,
+		"gpt-4o":           false,
+		"qwen2.5:0.5b":     false,
+	} {
+		ok, err := c.HasOllamaModel(ctx, name)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, name)
+	}
+}
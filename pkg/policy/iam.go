@@ -0,0 +1,62 @@
+package policy
+
+import "fmt"
+
+// Grant is the set of actions one deployment feature needs on the resources
+// it owns, e.g. writing to the deployment's log group.
+type Grant struct {
+	Feature   string
+	Actions   []string
+	Resources []string
+}
+
+// CheckLeastPrivilege verifies that every action/resource pair allowed by the
+// IAM policy document is covered by one of grants. It returns a description
+// of each excess permission; an empty result means the policy is minimal.
+func CheckLeastPrivilege(document string, grants []Grant) ([]string, error) {
+	statements, err := parseStatements(document)
+	if err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+
+	var problems []string
+	for i, st := range statements {
+		if st.Effect != "Allow" {
+			continue
+		}
+		name := st.Sid
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		if len(st.NotAction) > 0 {
+			problems = append(problems, fmt.Sprintf("statement %s: NotAction is never least-privilege", name))
+		}
+
+		for _, action := range st.Action {
+			var granting []Grant
+			for _, g := range grants {
+				if contains(g.Actions, action) {
+					granting = append(granting, g)
+				}
+			}
+			if len(granting) == 0 {
+				problems = append(problems, fmt.Sprintf("statement %s: action %s is not required by any enabled feature", name, action))
+				continue
+			}
+
+			for _, resource := range st.Resource {
+				scoped := false
+				for _, g := range granting {
+					if contains(g.Resources, resource) {
+						scoped = true
+						break
+					}
+				}
+				if !scoped {
+					problems = append(problems, fmt.Sprintf("statement %s: %s on %s is outside the deployment's resources", name, action, resource))
+				}
+			}
+		}
+	}
+	return problems, nil
+}
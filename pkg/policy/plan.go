@@ -0,0 +1,152 @@
+// Package policy evaluates guardrail rules against the JSON form of a
+// Terraform plan, as produced by `terraform show -json plan.tfplan`.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Plan holds the resources planned by Terraform across all modules.
+type Plan struct {
+	FormatVersion    string
+	TerraformVersion string
+	Resources        []Resource
+}
+
+// Resource is a single planned resource or data source.
+type Resource struct {
+	Address string         `json:"address"`
+	Mode    string         `json:"mode"`
+	Type    string         `json:"type"`
+	Name    string         `json:"name"`
+	Values  map[string]any `json:"values"`
+}
+
+type planModule struct {
+	Resources    []Resource   `json:"resources"`
+	ChildModules []planModule `json:"child_modules"`
+}
+
+type planJSON struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	PlannedValues    struct {
+		RootModule planModule `json:"root_module"`
+	} `json:"planned_values"`
+}
+
+// LoadPlan decodes `terraform show -json` output.
+func LoadPlan(r io.Reader) (*Plan, error) {
+	var raw planJSON
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decode plan: %w", err)
+	}
+	if raw.FormatVersion == "" {
+		return nil, fmt.Errorf("decode plan: missing format_version, is this `terraform show -json` output?")
+	}
+
+	p := &Plan{FormatVersion: raw.FormatVersion, TerraformVersion: raw.TerraformVersion}
+	var walk func(m planModule)
+	walk = func(m planModule) {
+		p.Resources = append(p.Resources, m.Resources...)
+		for _, child := range m.ChildModules {
+			walk(child)
+		}
+	}
+	walk(raw.PlannedValues.RootModule)
+	return p, nil
+}
+
+// LoadPlanFile reads a plan JSON file from disk.
+func LoadPlanFile(path string) (*Plan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadPlan(f)
+}
+
+// ManagedResources returns the planned managed resources of the given types,
+// or all of them when no type is given.
+func (p *Plan) ManagedResources(types ...string) []Resource {
+	var out []Resource
+	for _, r := range p.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		if len(types) == 0 || contains(types, r.Type) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// blocks returns a nested block attribute as a list of objects. Nested blocks
+// are always rendered as lists in plan JSON, even for max_items = 1.
+func blocks(values map[string]any, key string) []map[string]any {
+	list, _ := values[key].([]any)
+	var out []map[string]any
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func stringValue(values map[string]any, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
+
+func stringList(values map[string]any, key string) []string {
+	list, _ := values[key].([]any)
+	var out []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func intValue(values map[string]any, key string) (int, bool) {
+	n, ok := values[key].(float64)
+	return int(n), ok
+}
+
+// boolValue accepts both booleans and the "true"/"false" strings some
+// resources (e.g. launch template EBS settings) use.
+func boolValue(values map[string]any, key string) bool {
+	switch v := values[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func stringMap(values map[string]any, key string) map[string]string {
+	m, _ := values[key].(map[string]any)
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFixture(t *testing.T, name string) *Plan {
+	t.Helper()
+	p, err := LoadPlanFile("testdata/" + name)
+	require.NoError(t, err)
+	return p
+}
+
+func findingsFor(r *Report, ruleID string) []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.RuleID == ruleID {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestLoadPlanRejectsNonPlanJSON(t *testing.T) {
+	_, err := LoadPlan(strings.NewReader(`{"values": {}}`))
+	assert.ErrorContains(t, err, "format_version")
+}
+
+func TestLoadPlanWalksChildModules(t *testing.T) {
+	p, err := LoadPlan(strings.NewReader(`{
+		"format_version": "1.2",
+		"planned_values": {"root_module": {
+			"resources": [{"address": "aws_vpc.a", "mode": "managed", "type": "aws_vpc"}],
+			"child_modules": [{"resources": [{"address": "module.n.aws_subnet.b", "mode": "managed", "type": "aws_subnet"}]}]
+		}}
+	}`))
+	require.NoError(t, err)
+	assert.Len(t, p.ManagedResources(), 2)
+	assert.Len(t, p.ManagedResources("aws_subnet"), 1)
+}
+
+// The plan of main.tf before the submodule split must trip several
+// guardrails. CI checks the plan of the current module.
+func TestBaselinePlanFails(t *testing.T) {
+	report := Evaluate(loadFixture(t, "baseline.plan.json"), DefaultConfig())
+
+	assert.True(t, report.Failed())
+	assert.ElementsMatch(t, []string{"SG001", "EBS001", "EC2001", "TAG001"}, report.FailedRules())
+
+	var openPorts []string
+	for _, f := range findingsFor(report, "SG001") {
+		assert.Equal(t, "aws_security_group.app", f.Address)
+		openPorts = append(openPorts, f.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"ingress on port 22 is open to 0.0.0.0/0",
+		"ingress on port 8080 is open to 0.0.0.0/0",
+		"ingress on port 11434 is open to 0.0.0.0/0",
+	}, openPorts)
+
+	assert.Equal(t, []Finding{{
+		RuleID: "EC2001", Severity: SeverityError, Address: "aws_instance.app",
+		Message: "metadata_options.http_tokens is unset, want required",
+	}}, findingsFor(report, "EC2001"))
+
+	tagged := map[string]string{}
+	for _, f := range findingsFor(report, "TAG001") {
+		tagged[f.Address] = f.Message
+	}
+	assert.Equal(t, "missing tags: Environment, ManagedBy", tagged["aws_vpc.main"])
+	assert.Equal(t, "missing tags: ManagedBy", tagged["aws_cloudwatch_log_group.app_logs"])
+	assert.NotContains(t, tagged, "aws_instance.app")
+	assert.NotContains(t, tagged, "aws_route_table_association.main", "resources without tags are not taggable")
+}
+
+func TestCompliantPlanPasses(t *testing.T) {
+	report := Evaluate(loadFixture(t, "compliant.plan.json"), DefaultConfig())
+
+	assert.Empty(t, report.Findings)
+	assert.False(t, report.Failed())
+}
+
+func TestViolations(t *testing.T) {
+	report := Evaluate(loadFixture(t, "violations.plan.json"), DefaultConfig())
+
+	messages := map[string][]string{}
+	for _, f := range report.Findings {
+		messages[f.RuleID] = append(messages[f.RuleID], f.Address+": "+f.Message)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"aws_security_group_rule.all: ingress on all ports is open to 0.0.0.0/0",
+		"aws_vpc_security_group_ingress_rule.range: ingress on ports 8000-9000 is open to ::/0",
+	}, messages["SG001"])
+	assert.ElementsMatch(t, []string{
+		"aws_ebs_volume.models: volume is not encrypted",
+		"aws_launch_template.app: block device /dev/sda1 is not encrypted",
+	}, messages["EBS001"])
+	assert.Equal(t, []string{
+		"aws_launch_template.app: metadata_options.http_tokens is optional, want required",
+	}, messages["EC2001"])
+	assert.ElementsMatch(t, []string{
+		`aws_iam_policy.admin: statement 0 allows wildcard action "*"`,
+		`aws_iam_role_policy.s3: statement 0 allows wildcard action "s3:Get*"`,
+		"aws_iam_role_policy.s3: statement 1 allows NotAction, which grants every other action",
+		`data.aws_iam_policy_document.claim: statement 0 allows wildcard action "ec2:*Address"`,
+	}, messages["IAM001"])
+	assert.Equal(t, []string{
+		"aws_cloudwatch_log_group.short: retention_in_days is 7, want at least 30",
+	}, messages["LOG001"])
+}
+
+func TestConfigAllowsPortsAndRetention(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AllowedIngressPorts = []int{22, 8080, 11434}
+	cfg.RequiredTags = nil
+
+	report := Evaluate(loadFixture(t, "baseline.plan.json"), cfg)
+	assert.ElementsMatch(t, []string{"EBS001", "EC2001"}, report.FailedRules())
+
+	cfg.MinLogRetentionDays = 90
+	report = Evaluate(loadFixture(t, "baseline.plan.json"), cfg)
+	assert.Contains(t, report.FailedRules(), "LOG001")
+}
+
+func TestReportFormats(t *testing.T) {
+	report := Evaluate(loadFixture(t, "violations.plan.json"), DefaultConfig())
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "RULE")
+	assert.Contains(t, text.String(), "aws_ebs_volume.models")
+	assert.Contains(t, text.String(), "violation(s) of 5 rule(s)")
+
+	var js bytes.Buffer
+	require.NoError(t, report.WriteJSON(&js))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	assert.Equal(t, report.Findings, decoded.Findings)
+	assert.Equal(t, report.ResourcesChecked, decoded.ResourcesChecked)
+
+	var sarif bytes.Buffer
+	require.NoError(t, report.WriteSARIF(&sarif))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(Rules))
+	assert.Len(t, log.Runs[0].Results, len(report.Findings))
+	for _, res := range log.Runs[0].Results {
+		assert.Equal(t, res.RuleID, log.Runs[0].Tool.Driver.Rules[res.RuleIndex].ID)
+	}
+
+	var clean bytes.Buffer
+	require.NoError(t, Evaluate(loadFixture(t, "compliant.plan.json"), DefaultConfig()).WriteText(&clean))
+	assert.Equal(t, "No policy violations in 7 resources.\n", clean.String())
+}
+
+func TestCheckLeastPrivilege(t *testing.T) {
+	logGroup := "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/logs"
+	grants := []Grant{{
+		Feature:   "logs",
+		Actions:   []string{"logs:CreateLogStream", "logs:PutLogEvents"},
+		Resources: []string{logGroup, logGroup + ":*"},
+	}}
+
+	problems, err := CheckLeastPrivilege(`{"Statement": [{
+		"Sid": "Logs", "Effect": "Allow",
+		"Action": ["logs:CreateLogStream", "logs:PutLogEvents"],
+		"Resource": ["`+logGroup+`", "`+logGroup+`:*"]
+	}]}`, grants)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	problems, err = CheckLeastPrivilege(`{"Statement": [
+		{"Sid": "Logs", "Effect": "Allow", "Action": "logs:PutLogEvents", "Resource": "*"},
+		{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::models/*"},
+		{"Effect": "Deny", "Action": "*", "Resource": "*"}
+	]}`, grants)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"statement Logs: logs:PutLogEvents on * is outside the deployment's resources",
+		"statement #1: action s3:GetObject is not required by any enabled feature",
+	}, problems)
+
+	_, err = CheckLeastPrivilege(`not json`, grants)
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Finding is a single rule violation.
+type Finding struct {
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	Address  string   `json:"address"`
+	Message  string   `json:"message"`
+}
+
+// Report is the outcome of evaluating the rules against a plan.
+type Report struct {
+	Rules            []Rule    `json:"-"`
+	ResourcesChecked int       `json:"resources_checked"`
+	Findings         []Finding `json:"findings"`
+}
+
+// Evaluate runs every rule in Rules against p.
+func Evaluate(p *Plan, cfg Config) *Report {
+	report := &Report{Rules: Rules, ResourcesChecked: len(p.Resources), Findings: []Finding{}}
+	for _, rule := range Rules {
+		for _, f := range rule.check(p, cfg) {
+			f.RuleID = rule.ID
+			f.Severity = rule.Severity
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	return report
+}
+
+// Failed reports whether any finding has error severity.
+func (r *Report) Failed() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// FailedRules returns the IDs of the rules with at least one finding.
+func (r *Report) FailedRules() []string {
+	var ids []string
+	for _, f := range r.Findings {
+		if !contains(ids, f.RuleID) {
+			ids = append(ids, f.RuleID)
+		}
+	}
+	return ids
+}
+
+// WriteText writes a human-readable table of findings.
+func (r *Report) WriteText(w io.Writer) error {
+	if len(r.Findings) == 0 {
+		_, err := fmt.Fprintf(w, "No policy violations in %d resources.\n", r.ResourcesChecked)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tSEVERITY\tRESOURCE\tMESSAGE")
+	for _, f := range r.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.RuleID, f.Severity, f.Address, f.Message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d violation(s) of %d rule(s) in %d resources.\n", len(r.Findings), len(r.FailedRules()), r.ResourcesChecked)
+	return err
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level Severity `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Severity        `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log for code scanning tools.
+// Findings are located by resource address since plan JSON carries no
+// source positions.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	driver := sarifDriver{
+		Name:           "ds-aws-policy",
+		InformationURI: "https://github.com/rfomerand/ds_aws",
+	}
+	index := map[string]int{}
+	for i, rule := range r.Rules {
+		sr := sarifRule{ID: rule.ID, ShortDescription: sarifMessage{rule.Description}}
+		sr.DefaultConfiguration.Level = rule.Severity
+		driver.Rules = append(driver.Rules, sr)
+		index[rule.ID] = i
+	}
+
+	results := []sarifResult{}
+	for _, f := range r.Findings {
+		results = append(results, sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: index[f.RuleID],
+			Level:     f.Severity,
+			Message:   sarifMessage{fmt.Sprintf("%s: %s", f.Address, f.Message)},
+			Locations: []sarifLocation{{
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: f.Address, Kind: "resource"}},
+			}},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
@@ -0,0 +1,379 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity of a finding, using SARIF's level names.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Config tunes the built-in rules.
+type Config struct {
+	// AllowedIngressPorts may be opened to 0.0.0.0/0 or ::/0.
+	AllowedIngressPorts []int
+	// MinLogRetentionDays is the shortest acceptable CloudWatch log retention.
+	MinLogRetentionDays int
+	// RequiredTags must be present on every taggable resource.
+	RequiredTags []string
+}
+
+// DefaultConfig returns the guardrails CI enforces.
+func DefaultConfig() Config {
+	return Config{
+		AllowedIngressPorts: []int{443},
+		MinLogRetentionDays: 30,
+		RequiredTags:        []string{"Environment", "ManagedBy"},
+	}
+}
+
+// Rule is a single guardrail evaluated against a plan.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	check       func(*Plan, Config) []Finding
+}
+
+// Rules are the built-in guardrails, in report order.
+var Rules = []Rule{
+	{
+		ID:          "SG001",
+		Description: "Security groups must not allow ingress from 0.0.0.0/0 or ::/0 except on allow-listed ports",
+		Severity:    SeverityError,
+		check:       checkOpenIngress,
+	},
+	{
+		ID:          "EBS001",
+		Description: "EBS volumes must be encrypted",
+		Severity:    SeverityError,
+		check:       checkEBSEncryption,
+	},
+	{
+		ID:          "EC2001",
+		Description: "Instances must require IMDSv2 session tokens",
+		Severity:    SeverityError,
+		check:       checkIMDSv2,
+	},
+	{
+		ID:          "IAM001",
+		Description: "IAM policies must not allow wildcard actions",
+		Severity:    SeverityError,
+		check:       checkIAMWildcards,
+	},
+	{
+		ID:          "LOG001",
+		Description: "CloudWatch log groups must retain logs for the minimum number of days",
+		Severity:    SeverityWarning,
+		check:       checkLogRetention,
+	},
+	{
+		ID:          "TAG001",
+		Description: "Taggable resources must carry the required tags",
+		Severity:    SeverityWarning,
+		check:       checkRequiredTags,
+	},
+}
+
+var worldCIDRs = []string{"0.0.0.0/0", "::/0"}
+
+type ingressRule struct {
+	from, to int
+	protocol string
+	cidrs    []string
+}
+
+func ingressRules(r Resource) []ingressRule {
+	switch r.Type {
+	case "aws_security_group":
+		var out []ingressRule
+		for _, b := range blocks(r.Values, "ingress") {
+			from, _ := intValue(b, "from_port")
+			to, _ := intValue(b, "to_port")
+			cidrs := append(stringList(b, "cidr_blocks"), stringList(b, "ipv6_cidr_blocks")...)
+			out = append(out, ingressRule{from, to, stringValue(b, "protocol"), cidrs})
+		}
+		return out
+	case "aws_security_group_rule":
+		if stringValue(r.Values, "type") != "ingress" {
+			return nil
+		}
+		from, _ := intValue(r.Values, "from_port")
+		to, _ := intValue(r.Values, "to_port")
+		cidrs := append(stringList(r.Values, "cidr_blocks"), stringList(r.Values, "ipv6_cidr_blocks")...)
+		return []ingressRule{{from, to, stringValue(r.Values, "protocol"), cidrs}}
+	case "aws_vpc_security_group_ingress_rule":
+		from, _ := intValue(r.Values, "from_port")
+		to, _ := intValue(r.Values, "to_port")
+		var cidrs []string
+		for _, key := range []string{"cidr_ipv4", "cidr_ipv6"} {
+			if cidr := stringValue(r.Values, key); cidr != "" {
+				cidrs = append(cidrs, cidr)
+			}
+		}
+		return []ingressRule{{from, to, stringValue(r.Values, "ip_protocol"), cidrs}}
+	}
+	return nil
+}
+
+func checkOpenIngress(p *Plan, cfg Config) []Finding {
+	var findings []Finding
+	for _, r := range p.ManagedResources("aws_security_group", "aws_security_group_rule", "aws_vpc_security_group_ingress_rule") {
+		for _, rule := range ingressRules(r) {
+			var open []string
+			for _, cidr := range rule.cidrs {
+				if contains(worldCIDRs, cidr) {
+					open = append(open, cidr)
+				}
+			}
+			if len(open) == 0 {
+				continue
+			}
+
+			allPorts := rule.protocol == "-1" || rule.protocol == "all"
+			if !allPorts && rule.from == rule.to && contains(cfg.AllowedIngressPorts, rule.from) {
+				continue
+			}
+
+			ports := fmt.Sprintf("port %d", rule.from)
+			switch {
+			case allPorts:
+				ports = "all ports"
+			case rule.from != rule.to:
+				ports = fmt.Sprintf("ports %d-%d", rule.from, rule.to)
+			}
+			findings = append(findings, Finding{
+				Address: r.Address,
+				Message: fmt.Sprintf("ingress on %s is open to %s", ports, strings.Join(open, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+func checkEBSEncryption(p *Plan, _ Config) []Finding {
+	var findings []Finding
+	flag := func(r Resource, what string) {
+		findings = append(findings, Finding{Address: r.Address, Message: what + " is not encrypted"})
+	}
+
+	for _, r := range p.ManagedResources("aws_instance", "aws_ebs_volume", "aws_launch_template") {
+		switch r.Type {
+		case "aws_instance":
+			for _, b := range blocks(r.Values, "root_block_device") {
+				if !boolValue(b, "encrypted") {
+					flag(r, "root_block_device")
+				}
+			}
+			for _, b := range blocks(r.Values, "ebs_block_device") {
+				if !boolValue(b, "encrypted") {
+					flag(r, fmt.Sprintf("ebs_block_device %s", stringValue(b, "device_name")))
+				}
+			}
+		case "aws_ebs_volume":
+			if !boolValue(r.Values, "encrypted") {
+				flag(r, "volume")
+			}
+		case "aws_launch_template":
+			for _, mapping := range blocks(r.Values, "block_device_mappings") {
+				for _, ebs := range blocks(mapping, "ebs") {
+					if !boolValue(ebs, "encrypted") {
+						flag(r, fmt.Sprintf("block device %s", stringValue(mapping, "device_name")))
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func checkIMDSv2(p *Plan, _ Config) []Finding {
+	var findings []Finding
+	for _, r := range p.ManagedResources("aws_instance", "aws_launch_template") {
+		tokens := ""
+		for _, b := range blocks(r.Values, "metadata_options") {
+			tokens = stringValue(b, "http_tokens")
+		}
+		if tokens != "required" {
+			if tokens == "" {
+				tokens = "unset"
+			}
+			findings = append(findings, Finding{
+				Address: r.Address,
+				Message: fmt.Sprintf("metadata_options.http_tokens is %s, want required", tokens),
+			})
+		}
+	}
+	return findings
+}
+
+var policyAttributes = map[string]string{
+	"aws_iam_policy":       "policy",
+	"aws_iam_role_policy":  "policy",
+	"aws_iam_user_policy":  "policy",
+	"aws_iam_group_policy": "policy",
+}
+
+// policyStatement accepts both the single-string and list forms IAM allows.
+type policyStatement struct {
+	Sid       string
+	Effect    string
+	Action    stringOrList
+	NotAction stringOrList
+	Resource  stringOrList
+}
+
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = []string{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+func parseStatements(document string) ([]policyStatement, error) {
+	var doc struct {
+		Statement json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+		return nil, err
+	}
+	var list []policyStatement
+	if err := json.Unmarshal(doc.Statement, &list); err == nil {
+		return list, nil
+	}
+	var single policyStatement
+	if err := json.Unmarshal(doc.Statement, &single); err != nil {
+		return nil, err
+	}
+	return []policyStatement{single}, nil
+}
+
+func checkIAMWildcards(p *Plan, _ Config) []Finding {
+	var findings []Finding
+	for _, r := range p.Resources {
+		attr, ok := policyAttributes[r.Type]
+		if r.Type == "aws_iam_policy_document" && r.Mode == "data" {
+			attr, ok = "json", true
+		}
+		if !ok {
+			continue
+		}
+		document := stringValue(r.Values, attr)
+		var statements []policyStatement
+		switch {
+		case document != "":
+			var err error
+			if statements, err = parseStatements(document); err != nil {
+				findings = append(findings, Finding{Address: r.Address, Message: fmt.Sprintf("cannot parse policy: %v", err)})
+				continue
+			}
+		case r.Type == "aws_iam_policy_document":
+			// A document that refers to resources yet to be created is read
+			// during apply, so only its statement blocks are known
+			statements = documentStatements(r.Values)
+		}
+		for i, st := range statements {
+			if st.Effect != "Allow" {
+				continue
+			}
+			if len(st.NotAction) > 0 {
+				findings = append(findings, Finding{
+					Address: r.Address,
+					Message: fmt.Sprintf("statement %d allows NotAction, which grants every other action", i),
+				})
+			}
+			for _, action := range st.Action {
+				if strings.Contains(action, "*") {
+					findings = append(findings, Finding{
+						Address: r.Address,
+						Message: fmt.Sprintf("statement %d allows wildcard action %q", i, action),
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// documentStatements returns the statement blocks of an
+// aws_iam_policy_document, whose effect defaults to Allow.
+func documentStatements(values map[string]any) []policyStatement {
+	var out []policyStatement
+	for _, b := range blocks(values, "statement") {
+		effect := stringValue(b, "effect")
+		if effect == "" {
+			effect = "Allow"
+		}
+		out = append(out, policyStatement{
+			Sid:       stringValue(b, "sid"),
+			Effect:    effect,
+			Action:    stringList(b, "actions"),
+			NotAction: stringList(b, "not_actions"),
+		})
+	}
+	return out
+}
+
+func checkLogRetention(p *Plan, cfg Config) []Finding {
+	var findings []Finding
+	for _, r := range p.ManagedResources("aws_cloudwatch_log_group") {
+		days, ok := intValue(r.Values, "retention_in_days")
+		// 0 means the events never expire
+		if ok && days == 0 {
+			continue
+		}
+		if !ok || days < cfg.MinLogRetentionDays {
+			findings = append(findings, Finding{
+				Address: r.Address,
+				Message: fmt.Sprintf("retention_in_days is %d, want at least %d", days, cfg.MinLogRetentionDays),
+			})
+		}
+	}
+	return findings
+}
+
+func checkRequiredTags(p *Plan, cfg Config) []Finding {
+	var findings []Finding
+	for _, r := range p.ManagedResources() {
+		if _, taggable := r.Values["tags"]; !taggable {
+			if _, taggable = r.Values["tags_all"]; !taggable {
+				continue
+			}
+		}
+
+		tags := stringMap(r.Values, "tags")
+		for k, v := range stringMap(r.Values, "tags_all") {
+			tags[k] = v
+		}
+		var missing []string
+		for _, key := range cfg.RequiredTags {
+			if _, ok := tags[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			findings = append(findings, Finding{
+				Address: r.Address,
+				Message: "missing tags: " + strings.Join(missing, ", "),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,152 @@
+// Package snapshot captures a deployment's Ollama model volume as an EBS
+// snapshot, tagged so later deployments can restore it through the module's
+// model_volume_snapshot_tags instead of pulling from the registry.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Tags written on every model snapshot.
+const (
+	TagPurpose       = "Purpose"
+	TagModels        = "OllamaModels"
+	TagOllamaVersion = "OllamaVersion"
+	TagDeployment    = "DeploymentId"
+
+	// Purpose matches the tag the module puts on its model volumes.
+	Purpose = "ollama-models"
+)
+
+// maxTagValue is the EC2 limit on tag value length.
+const maxTagValue = 256
+
+// EC2API is the subset of the EC2 client used here.
+type EC2API interface {
+	DescribeVolumes(ctx context.Context, in *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	CreateSnapshot(ctx context.Context, in *ec2.CreateSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotOutput, error)
+	DescribeSnapshots(ctx context.Context, in *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error)
+}
+
+// Request describes the snapshot to take.
+type Request struct {
+	VolumeID      string
+	DeploymentID  string
+	Models        []string
+	OllamaVersion string
+	// Tags are extra tags, e.g. to tell snapshots of different model sets apart.
+	Tags map[string]string
+}
+
+// FindVolume returns the ID of the model volume the module created for
+// deploymentID (the deployment_id output).
+func FindVolume(ctx context.Context, api EC2API, deploymentID string) (string, error) {
+	out, err := api.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("tag:Name"), Values: []string{deploymentID + "-models"}},
+			{Name: aws.String("tag:" + TagPurpose), Values: []string{Purpose}},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("describe volumes: %w", err)
+	}
+	switch len(out.Volumes) {
+	case 0:
+		return "", fmt.Errorf("no model volume found for deployment %s; was it created with model_volume_size or a snapshot?", deploymentID)
+	case 1:
+		return aws.ToString(out.Volumes[0].VolumeId), nil
+	default:
+		return "", fmt.Errorf("%d model volumes found for deployment %s", len(out.Volumes), deploymentID)
+	}
+}
+
+// Tags returns the tags describing the snapshot for req.
+func Tags(req Request) []types.Tag {
+	values := map[string]string{}
+	for k, v := range req.Tags {
+		values[k] = v
+	}
+	values[TagPurpose] = Purpose
+	values[TagModels] = modelsValue(req.Models)
+	if req.OllamaVersion != "" {
+		values[TagOllamaVersion] = req.OllamaVersion
+	}
+	if req.DeploymentID != "" {
+		values[TagDeployment] = req.DeploymentID
+		values["Name"] = req.DeploymentID + "-models-" + time.Now().UTC().Format("20060102-150405")
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]types.Tag, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(values[k])})
+	}
+	return tags
+}
+
+// modelsValue joins the sorted model names, dropping the ones that do not
+// fit in a tag value and noting how many were left out.
+func modelsValue(models []string) string {
+	sorted := append([]string(nil), models...)
+	sort.Strings(sorted)
+	value := strings.Join(sorted, ",")
+	if len(value) <= maxTagValue {
+		return value
+	}
+	for n := len(sorted) - 1; n > 0; n-- {
+		value = fmt.Sprintf("%s,+%d more", strings.Join(sorted[:n], ","), len(sorted)-n)
+		if len(value) <= maxTagValue {
+			return value
+		}
+	}
+	return fmt.Sprintf("%d models", len(sorted))
+}
+
+// Create starts a snapshot of req.VolumeID and returns its ID. The snapshot
+// is crash-consistent; Ollama writes blobs under temporary names and renames
+// them when complete, so a finished pull is always captured whole.
+func Create(ctx context.Context, api EC2API, req Request) (string, error) {
+	if req.VolumeID == "" {
+		return "", errors.New("volume ID is required")
+	}
+	if len(req.Models) == 0 {
+		return "", errors.New("refusing to snapshot a model volume without models")
+	}
+
+	out, err := api.CreateSnapshot(ctx, &ec2.CreateSnapshotInput{
+		VolumeId:    aws.String(req.VolumeID),
+		Description: aws.String("Ollama model store: " + modelsValue(req.Models)),
+		TagSpecifications: []types.TagSpecification{{
+			ResourceType: types.ResourceTypeSnapshot,
+			Tags:         Tags(req),
+		}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create snapshot of %s: %w", req.VolumeID, err)
+	}
+	return aws.ToString(out.SnapshotId), nil
+}
+
+// Wait blocks until snapshotID completes or maxWait elapses. Snapshots of
+// large model stores take a while; the first one copies every block.
+func Wait(ctx context.Context, api EC2API, snapshotID string, maxWait time.Duration, optFns ...func(*ec2.SnapshotCompletedWaiterOptions)) error {
+	waiter := ec2.NewSnapshotCompletedWaiter(api, optFns...)
+	err := waiter.Wait(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: []string{snapshotID}}, maxWait)
+	if err != nil {
+		return fmt.Errorf("wait for snapshot %s: %w", snapshotID, err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 records snapshot requests and reports them pending for a number of
+// describe calls before completing.
+type fakeEC2 struct {
+	volumes      []types.Volume
+	created      []*ec2.CreateSnapshotInput
+	pendingPolls int
+	failState    types.SnapshotState
+	polls        int
+}
+
+func (f *fakeEC2) DescribeVolumes(_ context.Context, in *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	var out []types.Volume
+	for _, v := range f.volumes {
+		if matches(v.Tags, in.Filters) {
+			out = append(out, v)
+		}
+	}
+	return &ec2.DescribeVolumesOutput{Volumes: out}, nil
+}
+
+func matches(tags []types.Tag, filters []types.Filter) bool {
+	for _, filter := range filters {
+		key := strings.TrimPrefix(aws.ToString(filter.Name), "tag:")
+		found := false
+		for _, tag := range tags {
+			if aws.ToString(tag.Key) == key && aws.ToString(tag.Value) == filter.Values[0] {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeEC2) CreateSnapshot(_ context.Context, in *ec2.CreateSnapshotInput, _ ...func(*ec2.Options)) (*ec2.CreateSnapshotOutput, error) {
+	f.created = append(f.created, in)
+	return &ec2.CreateSnapshotOutput{SnapshotId: aws.String(fmt.Sprintf("snap-%04d", len(f.created)))}, nil
+}
+
+func (f *fakeEC2) DescribeSnapshots(_ context.Context, in *ec2.DescribeSnapshotsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error) {
+	f.polls++
+	state := types.SnapshotStatePending
+	if f.polls > f.pendingPolls {
+		state = types.SnapshotStateCompleted
+		if f.failState != "" {
+			state = f.failState
+		}
+	}
+	return &ec2.DescribeSnapshotsOutput{Snapshots: []types.Snapshot{{
+		SnapshotId: aws.String(in.SnapshotIds[0]),
+		State:      state,
+	}}}, nil
+}
+
+func tagMap(tags []types.Tag) map[string]string {
+	m := map[string]string{}
+	for _, t := range tags {
+		m[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return m
+}
+
+func modelVolume(id, deployment string) types.Volume {
+	return types.Volume{
+		VolumeId: aws.String(id),
+		Tags: []types.Tag{
+			{Key: aws.String("Name"), Value: aws.String(deployment + "-models")},
+			{Key: aws.String("Purpose"), Value: aws.String("ollama-models")},
+		},
+	}
+}
+
+func TestFindVolume(t *testing.T) {
+	api := &fakeEC2{volumes: []types.Volume{
+		modelVolume("vol-a", "ds-0a1b2c3d"),
+		modelVolume("vol-b", "ds-ffffffff"),
+	}}
+	ctx := context.Background()
+
+	id, err := FindVolume(ctx, api, "ds-0a1b2c3d")
+	require.NoError(t, err)
+	assert.Equal(t, "vol-a", id)
+
+	_, err = FindVolume(ctx, api, "ds-00000000")
+	assert.ErrorContains(t, err, "no model volume found")
+}
+
+func TestCreateTagsSnapshot(t *testing.T) {
+	api := &fakeEC2{}
+
+	id, err := Create(context.Background(), api, Request{
+		VolumeID:      "vol-a",
+		DeploymentID:  "ds-0a1b2c3d",
+		Models:        []string{"qwen2.5:0.5b", "deepseek-r1:671b"},
+		OllamaVersion: "0.5.7",
+		Tags:          map[string]string{"Team": "ml", "Purpose": "ignored"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "snap-0001", id)
+
+	require.Len(t, api.created, 1)
+	in := api.created[0]
+	assert.Equal(t, "vol-a", aws.ToString(in.VolumeId))
+	require.Len(t, in.TagSpecifications, 1)
+	assert.Equal(t, types.ResourceTypeSnapshot, in.TagSpecifications[0].ResourceType)
+
+	tags := tagMap(in.TagSpecifications[0].Tags)
+	assert.Equal(t, "ollama-models", tags["Purpose"], "extra tags must not override Purpose")
+	assert.Equal(t, "deepseek-r1:671b,qwen2.5:0.5b", tags["OllamaModels"])
+	assert.Equal(t, "0.5.7", tags["OllamaVersion"])
+	assert.Equal(t, "ds-0a1b2c3d", tags["DeploymentId"])
+	assert.Equal(t, "ml", tags["Team"])
+	assert.True(t, strings.HasPrefix(tags["Name"], "ds-0a1b2c3d-models-"))
+}
+
+func TestCreateRequiresModels(t *testing.T) {
+	api := &fakeEC2{}
+
+	_, err := Create(context.Background(), api, Request{VolumeID: "vol-a"})
+	assert.Error(t, err)
+	assert.Empty(t, api.created)
+}
+
+func TestModelsValueFitsTagLimit(t *testing.T) {
+	var models []string
+	for i := 0; i < 40; i++ {
+		models = append(models, fmt.Sprintf("model-%02d:latest", i))
+	}
+
+	value := modelsValue(models)
+	assert.LessOrEqual(t, len(value), maxTagValue)
+	assert.True(t, strings.HasPrefix(value, "model-00:latest,model-01:latest"))
+	assert.Regexp(t, `,\+\d+ more$`, value)
+}
+
+func TestWait(t *testing.T) {
+	fast := func(o *ec2.SnapshotCompletedWaiterOptions) {
+		o.MinDelay = time.Millisecond
+		o.MaxDelay = time.Millisecond
+	}
+
+	api := &fakeEC2{pendingPolls: 2}
+	require.NoError(t, Wait(context.Background(), api, "snap-0001", time.Second, fast))
+	assert.Equal(t, 3, api.polls)
+
+	api = &fakeEC2{failState: types.SnapshotStateError}
+	err := Wait(context.Background(), api, "snap-0001", time.Second, fast)
+	assert.Error(t, err)
+
+	api = &fakeEC2{pendingPolls: 1 << 30}
+	err = Wait(context.Background(), api, "snap-0001", 20*time.Millisecond, fast)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, context.Canceled))
+}
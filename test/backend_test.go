@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backendFixture is a configuration with an empty s3 backend block, the way
+// backend.hcl.example tells callers to write theirs.
+const backendFixture = `terraform {
+  backend "s3" {}
+}
+
+output "backend" {
+  value = "s3"
+}
+`
+
+// TestStateBackendBootstrap applies modules/state-backend against LocalStack,
+// then initializes and applies a configuration whose state lives in it.
+func TestStateBackendBootstrap(t *testing.T) {
+	t.Parallel()
+
+	endpoint := localstackEndpoint(t)
+	region := "us-west-1"
+	suffix := strings.ToLower(random.UniqueId())
+	env := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "test",
+		"AWS_SECRET_ACCESS_KEY": "test",
+		"AWS_DEFAULT_REGION":    region,
+	}
+
+	bootstrapDir := test_structure.CopyTerraformFolderToTemp(t, "../", "modules/state-backend")
+	require.NoError(t, os.WriteFile(filepath.Join(bootstrapDir, "localstack_provider.tf"), []byte(localstackProvider(endpoint, region, "")), 0o644))
+
+	bootstrapOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: bootstrapDir,
+		Vars: map[string]interface{}{
+			"bucket_name":     "ds-aws-tfstate-" + suffix,
+			"lock_table_name": "ds-aws-tfstate-lock-" + suffix,
+			"force_destroy":   true,
+			"tags":            map[string]string{"Environment": "test", "ManagedBy": "terraform"},
+		},
+		EnvVars:            env,
+		MaxRetries:         3,
+		TimeBetweenRetries: 5 * time.Second,
+	})
+
+	defer terraform.Destroy(t, bootstrapOptions)
+	terraform.InitAndApply(t, bootstrapOptions)
+
+	bucket := terraform.Output(t, bootstrapOptions, "bucket")
+	table := terraform.Output(t, bootstrapOptions, "lock_table_name")
+	backendConfig := terraform.Output(t, bootstrapOptions, "backend_config")
+	assert.Contains(t, backendConfig, fmt.Sprintf("bucket         = %q", bucket))
+	assert.Contains(t, backendConfig, fmt.Sprintf("dynamodb_table = %q", table))
+	assert.Contains(t, backendConfig, "encrypt        = true")
+
+	// The partial config the module prints, plus what LocalStack needs
+	backendFile := filepath.Join(t.TempDir(), "backend.hcl")
+	require.NoError(t, os.WriteFile(backendFile, []byte(backendConfig+fmt.Sprintf(`
+use_path_style              = true
+skip_credentials_validation = true
+skip_requesting_account_id  = true
+skip_metadata_api_check     = true
+skip_s3_checksum            = true
+
+endpoints = {
+  s3       = %[1]q
+  dynamodb = %[1]q
+  iam      = %[1]q
+  sts      = %[1]q
+}
+`, endpoint)), 0o644))
+
+	fixtureDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(fixtureDir, "main.tf"), []byte(backendFixture), 0o644))
+
+	fixtureOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir:       fixtureDir,
+		EnvVars:            env,
+		MaxRetries:         3,
+		TimeBetweenRetries: 5 * time.Second,
+	})
+	terraform.RunTerraformCommand(t, fixtureOptions, "init", "-input=false", "-backend-config="+backendFile)
+	terraform.Apply(t, fixtureOptions)
+	assert.Equal(t, "s3", terraform.Output(t, fixtureOptions, "backend"))
+
+	ctx := context.Background()
+	cfg := localstackConfig(t, region)
+	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+		o.UsePathStyle = true
+	})
+	dynamodbClient := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) { o.BaseEndpoint = aws.String(endpoint) })
+
+	versioning, err := s3Client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucket)})
+	require.NoError(t, err)
+	assert.Equal(t, s3types.BucketVersioningStatusEnabled, versioning.Status)
+
+	encryption, err := s3Client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(bucket)})
+	require.NoError(t, err)
+	require.NotEmpty(t, encryption.ServerSideEncryptionConfiguration.Rules)
+	assert.Equal(t, s3types.ServerSideEncryptionAes256, encryption.ServerSideEncryptionConfiguration.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+
+	bucketTags, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	require.NoError(t, err)
+	tags := map[string]string{}
+	for _, tag := range bucketTags.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	assert.Equal(t, map[string]string{"Name": bucket, "Purpose": "terraform-state", "Environment": "test", "ManagedBy": "terraform"}, tags)
+
+	tableInfo, err := dynamodbClient.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	require.NoError(t, err)
+	tableTags, err := dynamodbClient.ListTagsOfResource(ctx, &dynamodb.ListTagsOfResourceInput{ResourceArn: tableInfo.Table.TableArn})
+	require.NoError(t, err)
+	tags = map[string]string{}
+	for _, tag := range tableTags.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	assert.Equal(t, map[string]string{"Name": table, "Purpose": "terraform-state-lock", "Environment": "test", "ManagedBy": "terraform"}, tags)
+
+	key := "ds_aws/terraform.tfstate"
+	_, err = s3Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	require.NoError(t, err, "apply should have written state to the bucket")
+
+	// The backend records the state digest in the lock table after each write
+	digest, err := dynamodbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key: map[string]dynamodbtypes.AttributeValue{
+			"LockID": &dynamodbtypes.AttributeValueMemberS{Value: bucket + "/" + key + "-md5"},
+		},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, digest.Item, "the lock table should hold the state digest")
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// bootstrapVars returns the template variables the compute module passes to
+// the bootstrap module for a standalone host.
+func bootstrapVars() map[string]interface{} {
+	return map[string]interface{}{
+		"node_role":                 "standalone",
+		"deployment_id":             "ds-0a1b2c3d",
+		"log_group_name":            "/ds-0a1b2c3d/logs",
+		"app_log_stream":            "ds-0a1b2c3d-{instance_id}",
+		"model_pull_log_group_name": "/ds-0a1b2c3d/model-pull",
+		"model_pull_stream":         "ds-0a1b2c3d-{instance_id}-model-pull",
+		"github_token":              testGitHubToken,
+		"aws_region":                "us-west-1",
+		"ollama_model":              "qwen2.5:0.5b",
+
+		"ollama_keep_alive":        "-1",
+		"ollama_num_parallel":      1,
+		"ollama_max_loaded_models": 1,
+		"ollama_context_length":    8192,
+		"ollama_num_threads":       0,
+		"ollama_warmup_models":     []string{},
+
+		"enable_api_proxy":          false,
+		"api_proxy_port":            443,
+		"api_proxy_tls_mode":        "self_signed",
+		"api_proxy_domain":          "",
+		"api_proxy_acme_email":      "",
+		"api_proxy_auth_mode":       "basic",
+		"api_proxy_basic_auth_user": "ollama",
+		"api_proxy_secret_arn":      "arn:aws:secretsmanager:us-west-1:000000000000:secret:ds-0a1b2c3d-api-proxy",
+		"dns_hostname":              "",
+
+		"model_cache_bucket": "",
+		"model_cache_prefix": "ollama/models",
+		"model_volume_id":    "",
+
+		"enable_autoscaling":     false,
+		"autoscaling_group_name": "ds-0a1b2c3d-asg",
+		"lifecycle_hook_name":    "ds-0a1b2c3d-claim-resources",
+		"eip_allocation_id":      "",
+
+		"inference_lb_dns_name": "",
+	}
+}
+
+// decodeUserData undoes the base64 and gzip encoding of cloud-init user
+// data and returns its MIME parts by filename, along with their content
+// types in order.
+func decodeUserData(t *testing.T, userData string) (map[string]string, []string) {
+	compressed, err := base64.StdEncoding.DecodeString(userData)
+	require.NoError(t, err)
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	parts := map[string]string{}
+	var types []string
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		parts[part.FileName()] = string(body)
+		types = append(types, contentType)
+	}
+	return parts, types
+}
+
+// bootstrapFile is one entry of the write_files list cloud-init receives.
+type bootstrapFile struct {
+	Path        string `yaml:"path"`
+	Owner       string `yaml:"owner"`
+	Permissions string `yaml:"permissions"`
+	Content     string `yaml:"content"`
+}
+
+// renderBootstrap applies a copy of the bootstrap module with vars and
+// returns its stage names, the files cloud-init writes and the script
+// cloud-init then runs.
+func renderBootstrap(t *testing.T, vars map[string]interface{}) ([]string, []bootstrapFile, string) {
+	moduleDir := test_structure.CopyTerraformFolderToTemp(t, "../", "modules/bootstrap")
+	terraformOptions := &terraform.Options{
+		TerraformDir: moduleDir,
+		Vars:         map[string]interface{}{"template_vars": vars},
+		NoColor:      true,
+	}
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	var stageNames []string
+	terraform.OutputStruct(t, terraformOptions, "stage_names", &stageNames)
+
+	parts, types := decodeUserData(t, terraform.Output(t, terraformOptions, "user_data"))
+	require.Equal(t, []string{"text/cloud-config", "text/x-shellscript"}, types)
+
+	var cloudConfig struct {
+		WriteFiles []bootstrapFile `yaml:"write_files"`
+	}
+	files := parts["bootstrap-files.yaml"]
+	require.True(t, strings.HasPrefix(files, "#cloud-config\n"))
+	require.NoError(t, yaml.Unmarshal([]byte(files), &cloudConfig))
+
+	return stageNames, cloudConfig.WriteFiles, parts["start.sh"]
+}
+
+// TestBootstrapUserData renders the bootstrap module for every node role and
+// checks that cloud-init receives the runner and each stage as root-only
+// files, then starts the runner, which runs the stages in order. Every script
+// must also parse.
+func TestBootstrapUserData(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform binary not found in PATH")
+	}
+
+	cases := []struct {
+		name      string
+		overrides map[string]interface{}
+		stages    []string
+	}{
+		{
+			name:   "standalone",
+			stages: []string{"cloudwatch", "packages", "docker", "app", "models"},
+		},
+		{
+			name:      "web",
+			overrides: map[string]interface{}{"node_role": "web", "inference_lb_dns_name": "internal-ds-0a1b2c3d-ollama.us-west-1.elb.amazonaws.com"},
+			stages:    []string{"cloudwatch", "packages", "docker", "app"},
+		},
+		{
+			name:      "inference",
+			overrides: map[string]interface{}{"node_role": "inference"},
+			stages:    []string{"cloudwatch", "packages", "docker", "app", "models"},
+		},
+		{
+			name:      "autoscaling",
+			overrides: map[string]interface{}{"enable_autoscaling": true, "eip_allocation_id": "eipalloc-0a1b2c3d", "model_volume_id": "vol-0a1b2c3d"},
+			stages:    []string{"cloudwatch", "packages", "docker", "claim", "app", "models"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vars := bootstrapVars()
+			for k, v := range tc.overrides {
+				vars[k] = v
+			}
+			stageNames, files, start := renderBootstrap(t, vars)
+			assert.Equal(t, tc.stages, stageNames)
+			assert.Contains(t, start, "exec /opt/ds-aws/bootstrap/run.sh")
+
+			// cloud-init writes the runner, lib.sh and every stage, readable by root only
+			scripts := map[string]string{}
+			for _, file := range files {
+				assert.Equal(t, "root:root", file.Owner, file.Path)
+				assert.Equal(t, "0700", file.Permissions, file.Path)
+				scripts[strings.TrimPrefix(file.Path, "/opt/ds-aws/bootstrap/")] = file.Content
+			}
+			var expected []string
+			for _, stage := range tc.stages {
+				expected = append(expected, stage+".sh")
+			}
+			expected = append(expected, "lib.sh", "run.sh")
+			var written []string
+			for name := range scripts {
+				written = append(written, name)
+			}
+			assert.ElementsMatch(t, expected, written)
+
+			// The runner runs every stage but models, which it backgrounds
+			var ran []string
+			for _, line := range strings.Split(scripts["run.sh"], "\n") {
+				if stage, ok := strings.CutPrefix(line, "run_stage "); ok {
+					ran = append(ran, stage)
+				}
+			}
+			var foreground []string
+			for _, stage := range tc.stages {
+				if stage != "models" {
+					foreground = append(foreground, stage)
+				}
+			}
+			assert.Equal(t, foreground, ran)
+			assert.Equal(t, tc.stages[len(tc.stages)-1] == "models", strings.Contains(scripts["run.sh"], `run.sh" models`))
+
+			for name, script := range scripts {
+				cmd := exec.Command("bash", "-n")
+				cmd.Stdin = strings.NewReader(script)
+				out, err := cmd.CombinedOutput()
+				assert.NoError(t, err, "%s does not parse: %s", name, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+#!/bin/bash
+# Sourced by every stub. record appends one line to the call log the harness
+# reads back, so tests can assert which commands a stage ran.
+
+CALL_LOG=/var/lib/dryrun/calls.log
+
+record() {
+    echo "$*" >> "$CALL_LOG"
+}
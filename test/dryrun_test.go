@@ -0,0 +1,488 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rfomerand/ds_aws/pkg/ollama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+)
+
+// fakeOllama imitates the Ollama API as the models stage uses it. Pulls
+// stream the layers of the model seeded into the container, and every
+// request is recorded.
+type fakeOllama struct {
+	layers    map[string]int64 // digest to size
+	pullError string           // returned by every pull when set
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	switch r.URL.Path {
+	case "/api/version":
+		fmt.Fprint(w, `{"version":"0.0.0-dryrun"}`)
+
+	case "/api/tags":
+		fmt.Fprint(w, `{"models":[]}`)
+
+	case "/api/pull":
+		enc.Encode(ollama.PullProgress{Status: "pulling manifest"})
+		if f.pullError != "" {
+			enc.Encode(ollama.PullProgress{Error: f.pullError})
+			return
+		}
+		for digest, size := range f.layers {
+			enc.Encode(ollama.PullProgress{Status: "pulling " + digest, Digest: digest, Total: size, Completed: size})
+		}
+		enc.Encode(ollama.PullProgress{Status: "verifying sha256 digest"})
+		enc.Encode(ollama.PullProgress{Status: "success"})
+
+	case "/api/show":
+		enc.Encode(map[string]interface{}{"details": ollama.ModelDetails{Format: "gguf", Family: "qwen2"}})
+
+	case "/api/create":
+		fmt.Fprint(w, `{"status":"success"}`)
+
+	case "/api/generate":
+		enc.Encode(ollama.GenerateResponse{Done: true, DoneReason: "load"})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeOllama) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// ollamaModelFiles returns a two-layer model as Ollama stores it under its
+// models directory, keyed by path below dir, along with the size of each
+// layer by digest.
+func ollamaModelFiles(dir, model string) (map[string][]byte, map[string]int64) {
+	name, tag, _ := strings.Cut(model, ":")
+	files := map[string][]byte{}
+	layers := map[string]int64{}
+	type layer struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Size      int64  `json:"size"`
+	}
+	blob := func(mediaType string, content []byte) layer {
+		sum := sha256.Sum256(content)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		files[path.Join(dir, "models/blobs", strings.Replace(digest, ":", "-", 1))] = content
+		layers[digest] = int64(len(content))
+		return layer{MediaType: mediaType, Digest: digest, Size: int64(len(content))}
+	}
+
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.docker.distribution.manifest.v2+json",
+		"config":        blob("application/vnd.docker.container.image.v1+json", []byte(`{"model_format":"gguf","model_family":"qwen2"}`)),
+		"layers":        []layer{blob("application/vnd.ollama.image.model", []byte("dry-run weights\n"))},
+	})
+	files[path.Join(dir, "models/manifests/registry.ollama.ai/library", name, tag)] = manifest
+	return files, layers
+}
+
+// dryRun is an Ubuntu 22.04 container holding a rendered bootstrap, as
+// cloud-init would have written it, with stubs in place of the network, AWS
+// and Docker (see test/dryrun). The container's Ollama is a fakeOllama on
+// the test host.
+type dryRun struct {
+	t         *testing.T
+	container testcontainers.Container
+}
+
+// startDryRun renders the bootstrap with vars and starts a container for it.
+// The test is skipped without terraform to render with or Docker to run in.
+func startDryRun(t *testing.T, vars map[string]interface{}, fake *fakeOllama) *dryRun {
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform binary not found in PATH")
+	}
+	if !dockerAvailable() {
+		t.Skip("Docker is not available")
+	}
+
+	_, bootstrapFiles, start := renderBootstrap(t, vars)
+
+	modelFiles, layers := ollamaModelFiles("/var/lib/dryrun/ollama", vars["ollama_model"].(string))
+	fake.layers = layers
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	var files []testcontainers.ContainerFile
+	add := func(name, content string, mode int64) {
+		files = append(files, testcontainers.ContainerFile{Reader: strings.NewReader(content), ContainerFilePath: name, FileMode: mode})
+	}
+	for _, file := range bootstrapFiles {
+		add(file.Path, file.Content, 0o700)
+	}
+	add("/var/lib/cloud/instance/scripts/start.sh", start, 0o700)
+	add("/var/lib/dryrun/ollama-url", fmt.Sprintf("http://%s:%d", testcontainers.HostInternal, port), 0o644)
+	for name, content := range modelFiles {
+		add(name, string(content), 0o644)
+	}
+
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			FromDockerfile:  testcontainers.FromDockerfile{Context: "dryrun"},
+			HostAccessPorts: []int{port},
+			Files:           files,
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err)
+
+	return &dryRun{t: t, container: container}
+}
+
+// exec runs cmd in the container and returns its exit code and output.
+func (d *dryRun) exec(cmd ...string) (int, string) {
+	code, reader, err := d.container.Exec(context.Background(), cmd, tcexec.Multiplexed())
+	require.NoError(d.t, err)
+	out, err := io.ReadAll(reader)
+	require.NoError(d.t, err)
+	return code, string(out)
+}
+
+// bootstrap runs the script cloud-init runs once the files are written.
+func (d *dryRun) bootstrap() int {
+	code, _ := d.exec("bash", "/var/lib/cloud/instance/scripts/start.sh")
+	return code
+}
+
+// readFile returns the content of path in the container, or "" when it
+// does not exist.
+func (d *dryRun) readFile(path string) string {
+	code, out := d.exec("cat", path)
+	if code != 0 {
+		return ""
+	}
+	return out
+}
+
+func (d *dryRun) exists(path string) bool {
+	code, _ := d.exec("test", "-e", path)
+	return code == 0
+}
+
+// calls returns the commands the stubs recorded, in order.
+func (d *dryRun) calls() []string {
+	return strings.Split(strings.TrimSpace(d.readFile("/var/lib/dryrun/calls.log")), "\n")
+}
+
+// logEvent is one line of deploy.log or model-pull.log. The model stage's
+// result event carries the outcome fields too.
+type logEvent struct {
+	Timestamp    string `json:"timestamp"`
+	DeploymentID string `json:"deployment_id"`
+	Stage        string `json:"stage"`
+	Level        string `json:"level"`
+	Attempt      *int   `json:"attempt"`
+	DurationMS   *int64 `json:"duration_ms"`
+	Message      string `json:"message"`
+
+	Event          string `json:"event"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason"`
+	Source         string `json:"source"`
+	OllamaVersion  string `json:"ollama_version"`
+	LayersVerified int    `json:"layers_verified"`
+}
+
+// logEvents parses a log file of the container. Every line must be a log
+// event.
+func (d *dryRun) logEvents(path string) []logEvent {
+	var events []logEvent
+	for _, line := range strings.Split(d.readFile(path), "\n") {
+		if line == "" {
+			continue
+		}
+		var event logEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			d.t.Errorf("%s has a line that is not JSON: %s", path, line)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// hasEvent reports whether events hold a message at level that starts with
+// prefix.
+func hasEvent(events []logEvent, level, prefix string) bool {
+	for _, event := range events {
+		if event.Level == level && strings.HasPrefix(event.Message, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForModels waits for the background model stage to report its outcome,
+// and to record its completion when it succeeded, and returns that report.
+func (d *dryRun) waitForModels() logEvent {
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		for _, event := range d.logEvents("/var/log/model-pull.log") {
+			if event.Event != "model_pull_result" {
+				continue
+			}
+			if event.Status != "ready" || d.exists("/var/lib/ds-aws/stages/models.done") {
+				return event
+			}
+		}
+		if time.Now().After(deadline) {
+			d.t.Fatalf("the model stage did not report a result:\n%s", d.readFile("/var/log/model-pull.log"))
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// stageReport is what a dry run tells about one stage. Tests print it as
+// JSON when a stage ends differently than expected.
+type stageReport struct {
+	Stage    string   `json:"stage"`
+	Status   string   `json:"status"` // completed, skipped, failed or incomplete
+	Attempts int      `json:"attempts"`
+	Log      []string `json:"log"`
+	Calls    []string `json:"stub_calls,omitempty"`
+}
+
+var (
+	stageAttemptMessage   = regexp.MustCompile(`^Stage \w+ attempt \d+ of \d+$`)
+	stageCompletedMessage = regexp.MustCompile(`^Stage \w+ completed$`)
+	stageSkippedMessage   = regexp.MustCompile(`^Stage \w+ already completed, skipping$`)
+	stageFailedMessage    = regexp.MustCompile(`^Stage \w+ failed after \d+ attempts$`)
+)
+
+// stageReports groups the log events of deploy.log and model-pull.log by
+// stage. The runner starts the model stage in the background without
+// logging, so its outcome comes from its result event and completion marker.
+func (d *dryRun) stageReports() map[string]*stageReport {
+	reports := map[string]*stageReport{}
+	events := append(d.logEvents("/var/log/deploy.log"), d.logEvents("/var/log/model-pull.log")...)
+	for _, event := range events {
+		if event.Stage == "runner" {
+			continue
+		}
+		r := reports[event.Stage]
+		if r == nil {
+			r = &stageReport{Stage: event.Stage, Status: "incomplete", Attempts: 1}
+			reports[event.Stage] = r
+		}
+		r.Log = append(r.Log, event.Level+": "+event.Message)
+		switch {
+		case stageAttemptMessage.MatchString(event.Message) && event.Attempt != nil:
+			r.Attempts = *event.Attempt
+		case stageCompletedMessage.MatchString(event.Message):
+			r.Status = "completed"
+		case stageSkippedMessage.MatchString(event.Message):
+			r.Status = "skipped"
+		case stageFailedMessage.MatchString(event.Message):
+			r.Status = "failed"
+		case event.Event == "model_pull_result" && event.Status == "failed":
+			r.Status = "failed"
+		}
+	}
+
+	if models := reports["models"]; models != nil && d.exists("/var/lib/ds-aws/stages/models.done") {
+		models.Status = "completed"
+	}
+	return reports
+}
+
+// requireStages checks that each stage ended with status, in the order
+// given, and prints the report of every stage that did not along with the
+// stub calls made so far.
+func (d *dryRun) requireStages(status string, stages ...string) {
+	reports := d.stageReports()
+	ok := true
+	for _, stage := range stages {
+		r := reports[stage]
+		if r == nil {
+			r = &stageReport{Stage: stage, Status: "not started"}
+		}
+		if r.Status != status {
+			r.Calls = d.calls()
+			out, _ := json.MarshalIndent(r, "", "  ")
+			d.t.Errorf("stage %s is %s, want %s:\n%s", stage, r.Status, status, out)
+			ok = false
+		}
+	}
+	if !ok {
+		d.t.FailNow()
+	}
+}
+
+func TestBootstrapDryRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("standalone", func(t *testing.T) {
+		t.Parallel()
+
+		vars := bootstrapVars()
+		fake := &fakeOllama{}
+		d := startDryRun(t, vars, fake)
+
+		require.Equal(t, 0, d.bootstrap())
+		d.requireStages("completed", "cloudwatch", "packages", "docker", "app")
+		result := d.waitForModels()
+		d.requireStages("completed", "models")
+
+		// Files
+		cwConfig := d.readFile("/opt/aws/amazon-cloudwatch-agent/bin/config.json")
+		var parsed map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(cwConfig), &parsed), "the CloudWatch agent configuration should be JSON")
+		assert.Contains(t, cwConfig, `"log_group_name": "/ds-0a1b2c3d/logs"`)
+		assert.Contains(t, cwConfig, `"log_group_name": "/ds-0a1b2c3d/model-pull"`)
+		assert.Contains(t, d.readFile("/etc/apt/apt.conf.d/80parallel-downloads"), `Acquire::Queue-Mode "host";`)
+		assert.Contains(t, d.readFile("/etc/apt/sources.list.d/docker.list"), "jammy stable")
+		assert.Contains(t, d.readFile("/etc/docker/daemon.json"), `"log-driver": "json-file"`)
+		assert.Equal(t, testGitHubToken+"\n", d.readFile("/root/.github-token"))
+		assert.True(t, d.exists("/home/ubuntu/ds_aws_docker/compose.yaml"))
+		assert.Contains(t, d.readFile("/home/ubuntu/compose.runtime.yaml"), `OLLAMA_KEEP_ALIVE: "-1"`)
+		for _, stage := range []string{"cloudwatch", "packages", "docker", "app", "models"} {
+			assert.True(t, d.exists("/var/lib/ds-aws/stages/"+stage+".done"), stage)
+		}
+		_, groups := d.exec("id", "-nG", "ubuntu")
+		assert.Contains(t, strings.Fields(groups), "docker")
+
+		// Services and the commands that set them up
+		calls := d.calls()
+		for _, call := range []string{
+			"wget https://s3.amazonaws.com/amazoncloudwatch-agent/ubuntu/amd64/latest/amazon-cloudwatch-agent.deb",
+			"amazon-cloudwatch-agent-ctl -a fetch-config -m ec2 -s -c file:/opt/aws/amazon-cloudwatch-agent/bin/config.json",
+			"systemctl start amazon-cloudwatch-agent",
+			"systemctl restart docker",
+			"git clone https://github.com/rfomerand/ds_aws_docker.git",
+			"docker compose up -d COMPOSE_FILE=compose.yaml:/home/ubuntu/compose.runtime.yaml",
+		} {
+			assert.Contains(t, calls, call)
+		}
+		for _, call := range calls {
+			assert.NotContains(t, call, testGitHubToken, "stubs should never see the token in a command line they record")
+		}
+		assert.Regexp(t, `(?m)^apt-get install .* jq$`, strings.Join(calls, "\n"))
+		assert.Regexp(t, `(?m)^apt-get install .* docker-compose-plugin$`, strings.Join(calls, "\n"))
+
+		// Log lines: every line is a JSON event the CloudWatch agent can
+		// timestamp, attributed to the deployment and a stage
+		deployLog := d.logEvents("/var/log/deploy.log")
+		pullLog := d.logEvents("/var/log/model-pull.log")
+		for _, event := range append(deployLog, pullLog...) {
+			_, err := time.Parse("2006-01-02T15:04:05.000Z", event.Timestamp)
+			assert.NoError(t, err, event.Message)
+			assert.Equal(t, "ds-0a1b2c3d", event.DeploymentID, event.Message)
+			assert.Contains(t, []string{"runner", "cloudwatch", "packages", "docker", "app", "models"}, event.Stage, event.Message)
+			assert.Contains(t, []string{"info", "warn", "error"}, event.Level, event.Message)
+		}
+		assert.True(t, hasEvent(deployLog, "info", "Starting deployment ds-0a1b2c3d (standalone) with "))
+		assert.True(t, hasEvent(deployLog, "info", "Docker compose successfully started"))
+		assert.True(t, hasEvent(deployLog, "info", "Base stages completed, waiting for the model stage"))
+		assert.False(t, hasEvent(deployLog, "info", "Deployment completed"), "the deployment completes after the models are warmed up")
+		for _, event := range deployLog {
+			if stageCompletedMessage.MatchString(event.Message) {
+				assert.NotNil(t, event.DurationMS, "%s should record how long the stage took", event.Message)
+			}
+		}
+		assert.True(t, hasEvent(pullLog, "info", "Model stage completed successfully"))
+		assert.True(t, hasEvent(pullLog, "info", "Deployment completed"))
+		assert.Equal(t, "ready", result.Status)
+		assert.Equal(t, "models", result.Stage)
+		assert.Equal(t, "registry", result.Source)
+		assert.Equal(t, "0.0.0-dryrun", result.OllamaVersion)
+		assert.Equal(t, 2, result.LayersVerified)
+
+		requests := fake.received()
+		for _, request := range []string{"POST /api/pull", "POST /api/show", "POST /api/create", "POST /api/generate"} {
+			assert.Contains(t, requests, request)
+		}
+
+		// A second run finds every stage done and changes nothing
+		before := len(calls)
+		require.Equal(t, 0, d.bootstrap())
+		d.requireStages("skipped", "cloudwatch", "packages", "docker", "app")
+		assert.Len(t, d.calls(), before, "a rerun should not repeat any stage")
+	})
+
+	t.Run("app stage failure", func(t *testing.T) {
+		t.Parallel()
+
+		vars := bootstrapVars()
+		vars["github_token"] = ""
+		d := startDryRun(t, vars, &fakeOllama{})
+
+		assert.NotEqual(t, 0, d.bootstrap())
+		d.requireStages("completed", "cloudwatch", "packages", "docker")
+		d.requireStages("failed", "app")
+
+		app := d.stageReports()["app"]
+		assert.Equal(t, 3, app.Attempts)
+		assert.Contains(t, app.Log, "error: github_token is not set")
+		assert.False(t, d.exists("/var/lib/ds-aws/stages/app.done"))
+		assert.Empty(t, d.readFile("/var/log/model-pull.log"), "the model stage should not start")
+		assert.False(t, hasEvent(d.logEvents("/var/log/deploy.log"), "info", "Deployment completed"))
+	})
+
+	t.Run("model pull failure", func(t *testing.T) {
+		t.Parallel()
+
+		d := startDryRun(t, bootstrapVars(), &fakeOllama{pullError: "pull model manifest: file does not exist"})
+
+		require.Equal(t, 0, d.bootstrap(), "the model stage runs in the background")
+		d.requireStages("completed", "cloudwatch", "packages", "docker", "app")
+		result := d.waitForModels()
+		d.requireStages("failed", "models")
+
+		assert.Equal(t, "failed", result.Status)
+		assert.Equal(t, "error", result.Level)
+		assert.Equal(t, "Failed to pull model after 3 attempts", result.Reason)
+		pullLog := d.logEvents("/var/log/model-pull.log")
+		assert.True(t, hasEvent(pullLog, "error", "Pull error: pull model manifest: file does not exist"))
+		assert.True(t, hasEvent(pullLog, "error", "Model pull attempt 3 failed"))
+		assert.True(t, hasEvent(pullLog, "error", "Failed to pull model after 3 attempts"))
+		assert.False(t, hasEvent(pullLog, "info", "Deployment completed"))
+		sleeps := 0
+		for _, call := range d.calls() {
+			if call == "sleep 60" {
+				sleeps++
+			}
+		}
+		assert.Equal(t, 2, sleeps, "pulls should back off between attempts")
+	})
+}
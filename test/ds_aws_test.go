@@ -1,27 +1,37 @@
-```go
-// terratest_plan_test.go
 package test
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/ssh"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rfomerand/ds_aws/pkg/ollama"
+	"github.com/rfomerand/ds_aws/pkg/openwebui"
 )
 
+// testModel is small enough to pull and answer within the test timeout.
+const testModel = "qwen2.5:0.5b"
+
 func TestTerraformAwsEc2(t *testing.T) {
 	t.Parallel()
 
-	// Random name for uniqueness
-	uniqueID := random.UniqueId()
-	keyPairName := fmt.Sprintf("terratest-ec2-keypair-%s", uniqueID)
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	if githubToken == "" {
+		t.Skip("GITHUB_TOKEN is not set; skipping apply against AWS")
+	}
 
 	// AWS Region
-	awsRegion := "us-east-1"
+	awsRegion := "us-west-1"
 
 	// Set up the Terraform options
 	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
@@ -30,11 +40,10 @@ func TestTerraformAwsEc2(t *testing.T) {
 
 		// Variables
 		Vars: map[string]interface{}{
-			"aws_region":       awsRegion,
-			"instance_keypair": keyPairName,
-			"instance_type":    "t2.xlarge",
-			"ami_id":           "ami-09eb231ad55c3963d",
-			"storage_size_gb":  100,
+			"instance_type":       "t3.xlarge",
+			"github_token":        githubToken,
+			"ssh_public_key_path": writePublicKey(t),
+			"ollama_model":        testModel,
 		},
 
 		// Environment variables
@@ -53,42 +62,79 @@ func TestTerraformAwsEc2(t *testing.T) {
 	// Initialize and apply Terraform
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Validate EC2 instance creation
-	instanceID := terraform.Output(t, terraformOptions, "instance_id")
-	assert.NotEmpty(t, instanceID, "Instance ID should not be empty")
+	deploymentID := terraform.Output(t, terraformOptions, "deployment_id")
+	assert.Regexp(t, `^ds-[0-9a-f]{8}$`, deploymentID)
+
+	publicIP := terraform.Output(t, terraformOptions, "public_ip")
+	assert.NotEmpty(t, publicIP, "Instance should have a public IP")
 
-	// Check that the instance is running
-	instanceStatus := aws.GetInstanceStatus(t, awsRegion, instanceID)
-	assert.Equal(t, "running", instanceStatus, "Instance should be in 'running' state")
+	// Validate the Ollama API answers with the configured model
+	apiURL := terraform.Output(t, terraformOptions, "ollama_api_url")
+	assert.Equal(t, fmt.Sprintf("http://%s:11434", publicIP), apiURL)
+	model := terraform.Output(t, terraformOptions, "ollama_model")
+	verifyOllamaModel(t, apiURL, model)
+
+	// Validate OpenWebUI is reachable and sees the Ollama backend
+	webURL := terraform.Output(t, terraformOptions, "openwebui_url")
+	assert.Equal(t, fmt.Sprintf("http://%s:8080", publicIP), webURL)
+	verifyOpenWebUI(t, webURL, model)
+}
 
-	// Validate storage
-	volumeID := terraform.Output(t, terraformOptions, "volume_id")
-	volumeSize := aws.GetVolumeSize(t, awsRegion, volumeID)
-	assert.Equal(t, int64(100), volumeSize, "EBS volume size should be 100 GB")
+// writePublicKey generates a throwaway key pair for aws_key_pair.app and
+// returns the path of its public half.
+func writePublicKey(t *testing.T) string {
+	keyPair := ssh.GenerateRSAKeyPair(t, 2048)
+	publicKeyPath := filepath.Join(t.TempDir(), "id_rsa.pub")
+	require.NoError(t, os.WriteFile(publicKeyPath, []byte(keyPair.PublicKey), 0600))
+	return publicKeyPath
+}
 
-	// Validate tag
-	instanceTags := aws.GetTagsForEc2Instance(t, awsRegion, instanceID)
-	assert.Contains(t, instanceTags, "bigo", "Instance should have a 'bigo' tag")
+// verifyOllamaModel waits for the bootstrap to finish pulling model and then
+// checks that it produces a short completion.
+func verifyOllamaModel(t *testing.T, apiURL, model string) {
+	client, err := ollama.NewClient(apiURL, ollama.WithTimeout(5*time.Minute))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	retry.DoWithRetry(t, fmt.Sprintf("Wait for %s to be listed", model), 90, 20*time.Second, func() (string, error) {
+		listed, err := client.HasModel(ctx, model)
+		if err != nil {
+			return "", err
+		}
+		if !listed {
+			return "", fmt.Errorf("model %s is not listed yet", model)
+		}
+		return "", nil
+	})
 
-	// Validate SSH Key Pair creation
-	keyPair, err := aws.GetEc2KeyPairE(t, awsRegion, keyPairName)
-	assert.NoError(t, err, "SSH Key Pair should be created and retrievable")
-	assert.Equal(t, keyPair.Name, keyPairName, "SSH Key Pair name should match")
+	resp, err := client.Generate(ctx, ollama.GenerateRequest{
+		Model:   model,
+		Prompt:  "Reply with the single word: ready",
+		Options: &ollama.Options{NumPredict: 8},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Done, "Generation should complete")
+	assert.NotEmpty(t, resp.Response, "Generation should return text")
 }
-```
 
-### Commit to Git
+// verifyOpenWebUI waits for OpenWebUI to become healthy, signs up the first
+// (admin) user of the fresh instance and checks the model list.
+func verifyOpenWebUI(t *testing.T, webURL, model string) {
+	client, err := openwebui.NewClient(webURL)
+	require.NoError(t, err)
 
-```bash
-# Create test directory if it doesn't exist
-mkdir -p test
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+	require.NoError(t, client.WaitHealthy(ctx, 15*time.Second))
 
-# Save the Terratest Go file into the test directory
-echo 'package test...
+	_, err = client.EnsureAdmin(ctx, openwebui.Credentials{
+		Name:     "Terratest",
+		Email:    "terratest@example.com",
+		Password: random.UniqueId() + random.UniqueId(),
+	})
+	require.NoError(t, err)
 
-# Commit the Terratest plan to the repository
-cd test
-git add terratest_plan_test.go
-git commit -m "Add Terratest plan for EC2 module"
-git push origin main
-```
\ No newline at end of file
+	listed, err := client.HasOllamaModel(ctx, model)
+	require.NoError(t, err)
+	assert.True(t, listed, "OpenWebUI should list %s from the Ollama backend", model)
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// example describes one directory under examples/: the provider aliases it
+// configures (just the default provider when empty), the variables it
+// requires, the module calls the plan must contain and the inference
+// instances each of them plans.
+type example struct {
+	dir       string
+	aliases   []string
+	vars      map[string]interface{}
+	modules   []string
+	inference int
+}
+
+var examples = []example{
+	{dir: "basic", modules: []string{"ds_aws"}},
+	{
+		dir: "api-proxy",
+		vars: map[string]interface{}{
+			"route53_zone_id": "Z0123456789ABCDEFGHIJ",
+			"hostname":        "ollama.example.com",
+			"acme_email":      "ops@example.com",
+		},
+		modules: []string{"ds_aws"},
+	},
+	{dir: "inference-pool", modules: []string{"ds_aws"}, inference: 3},
+	{
+		dir:     "multi-region",
+		aliases: []string{"west", "east"},
+		vars: map[string]interface{}{
+			"ami_ids": map[string]string{
+				"us-west-1": "ami-0123456789abcdef0",
+				"us-east-1": "ami-0fedcba9876543210",
+			},
+		},
+		modules: []string{"west", "east"},
+	},
+}
+
+// TestExamplesPlan plans every example against LocalStack. Each example
+// configures its own provider, which an override file points at LocalStack.
+func TestExamplesPlan(t *testing.T) {
+	t.Parallel()
+
+	endpoint := localstackEndpoint(t)
+	region := "us-west-1"
+
+	entries, err := os.ReadDir("../examples")
+	require.NoError(t, err)
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	var covered []string
+	for _, ex := range examples {
+		covered = append(covered, ex.dir)
+	}
+	require.ElementsMatch(t, dirs, covered, "every example should be planned")
+
+	for _, ex := range examples {
+		ex := ex
+		t.Run(ex.dir, func(t *testing.T) {
+			t.Parallel()
+
+			exampleDir := test_structure.CopyTerraformFolderToTemp(t, "../", filepath.Join("examples", ex.dir))
+			aliases := ex.aliases
+			if len(aliases) == 0 {
+				aliases = []string{""}
+			}
+			var overrides []string
+			for _, alias := range aliases {
+				overrides = append(overrides, localstackProvider(endpoint, "", alias))
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(exampleDir, "localstack_override.tf"), []byte(strings.Join(overrides, "\n")), 0o644))
+
+			vars := map[string]interface{}{
+				"github_token":        testGitHubToken,
+				"ssh_public_key_path": writePublicKey(t),
+			}
+			for k, v := range ex.vars {
+				vars[k] = v
+			}
+
+			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+				TerraformDir: exampleDir,
+				Vars:         vars,
+				PlanFilePath: filepath.Join(exampleDir, "example.tfplan"),
+
+				EnvVars: map[string]string{
+					"AWS_ACCESS_KEY_ID":     "test",
+					"AWS_SECRET_ACCESS_KEY": "test",
+					"AWS_DEFAULT_REGION":    region,
+				},
+
+				MaxRetries:         3,
+				TimeBetweenRetries: 5 * time.Second,
+			})
+			plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+			for _, module := range ex.modules {
+				prefix := "module." + module + "."
+				terraform.RequirePlannedValuesMapKeyExists(t, plan, prefix+"module.compute.aws_instance.app[0]")
+				terraform.RequirePlannedValuesMapKeyExists(t, plan, prefix+"module.network.aws_vpc.main")
+				terraform.RequirePlannedValuesMapKeyExists(t, plan, prefix+"module.iam.aws_iam_role.ec2_cloudwatch")
+				terraform.RequirePlannedValuesMapKeyExists(t, plan, prefix+"module.observability.aws_cloudwatch_log_group.app_logs")
+				assert.Equal(t, ex.inference, countPlanned(plan, prefix+"module.compute.aws_instance.inference"))
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rfomerand/ds_aws/pkg/policy"
+)
+
+// instanceGrants lists what each enabled feature legitimately needs, scoped
+// to the ARNs of the applied deployment.
+func instanceGrants(t *testing.T, opts *terraform.Options, ec2Client *ec2.Client) []policy.Grant {
+	logGroup := terraform.Output(t, opts, "log_group_arn")
+	modelPullGroup := terraform.Output(t, opts, "model_pull_log_group_arn")
+	grants := []policy.Grant{{
+		Feature: "logs",
+		Actions: []string{
+			"logs:CreateLogGroup",
+			"logs:CreateLogStream",
+			"logs:PutLogEvents",
+			"logs:DescribeLogStreams",
+		},
+		Resources: []string{logGroup, logGroup + ":*", modelPullGroup, modelPullGroup + ":*"},
+	}}
+
+	if secret := terraform.Output(t, opts, "api_proxy_secret_arn"); secret != "" {
+		grants = append(grants, policy.Grant{
+			Feature:   "api_proxy",
+			Actions:   []string{"secretsmanager:GetSecretValue"},
+			Resources: []string{secret},
+		})
+	}
+	if bucket := terraform.Output(t, opts, "model_cache_bucket"); bucket != "" {
+		grants = append(grants,
+			policy.Grant{
+				Feature:   "model_cache",
+				Actions:   []string{"s3:ListBucket"},
+				Resources: []string{"arn:aws:s3:::" + bucket},
+			},
+			policy.Grant{
+				Feature:   "model_cache",
+				Actions:   []string{"s3:GetObject", "s3:PutObject"},
+				Resources: []string{"arn:aws:s3:::" + bucket + "/ollama/models/*"},
+			},
+		)
+	}
+	if asg := terraform.Output(t, opts, "autoscaling_group_name"); asg != "" {
+		// arn:aws:logs:<region>:<account>:log-group:...
+		arn := strings.Split(logGroup, ":")
+		region, account := arn[3], arn[4]
+		instances := "arn:aws:ec2:" + region + ":" + account + ":instance/*"
+		grants = append(grants, policy.Grant{
+			Feature:   "autoscaling",
+			Actions:   []string{"autoscaling:CompleteLifecycleAction"},
+			Resources: []string{"arn:aws:autoscaling:" + region + ":" + account + ":autoScalingGroup:*:autoScalingGroupName/" + asg},
+		})
+		if volume := terraform.Output(t, opts, "model_volume_id"); volume != "" {
+			grants = append(grants, policy.Grant{
+				Feature:   "autoscaling",
+				Actions:   []string{"ec2:AttachVolume"},
+				Resources: []string{instances, "arn:aws:ec2:" + region + ":" + account + ":volume/" + volume},
+			})
+		}
+		if ip := terraform.Output(t, opts, "public_ip"); ip != "" {
+			addresses, err := ec2Client.DescribeAddresses(context.Background(), &ec2.DescribeAddressesInput{PublicIps: []string{ip}})
+			require.NoError(t, err)
+			require.Len(t, addresses.Addresses, 1)
+			grants = append(grants, policy.Grant{
+				Feature:   "autoscaling",
+				Actions:   []string{"ec2:AssociateAddress"},
+				Resources: []string{instances, "arn:aws:ec2:" + region + ":" + account + ":elastic-ip/" + aws.ToString(addresses.Addresses[0].AllocationId)},
+			})
+		}
+	}
+	return grants
+}
+
+func TestInstanceRoleLeastPrivilege(t *testing.T) {
+	t.Parallel()
+
+	endpoint := localstackEndpoint(t)
+	cfg := localstackConfig(t, "us-west-1")
+	ec2Client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.BaseEndpoint = aws.String(endpoint) })
+
+	features := map[string]map[string]interface{}{
+		"defaults":  nil,
+		"api_proxy": {"enable_api_proxy": true},
+		"model_cache": {
+			"create_model_cache_bucket": true,
+			"model_cache_force_destroy": true,
+		},
+		"autoscaling": {
+			"enable_autoscaling": true,
+			"create_eip":         true,
+			"model_volume_size":  20,
+		},
+	}
+	for name, vars := range features {
+		vars := vars
+		t.Run(name, func(t *testing.T) {
+			terraformOptions := localstackOptions(t, endpoint, "us-west-1", vars)
+			defer terraform.Destroy(t, terraformOptions)
+			terraform.InitAndApply(t, terraformOptions)
+
+			document := terraform.Output(t, terraformOptions, "instance_role_policy")
+			problems, err := policy.CheckLeastPrivilege(document, instanceGrants(t, terraformOptions, ec2Client))
+			require.NoError(t, err)
+			assert.Empty(t, problems, "instance role policy:\n%s", document)
+		})
+	}
+}
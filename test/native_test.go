@@ -0,0 +1,24 @@
+package test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// TestTerraformNativeTests runs tests/*.tftest.hcl so that `go test ./...`
+// also covers the offline, mocked-provider suite.
+func TestTerraformNativeTests(t *testing.T) {
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform binary not found in PATH")
+	}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../",
+		NoColor:      true,
+	}
+
+	terraform.RunTerraformCommand(t, terraformOptions, "init", "-backend=false", "-input=false")
+	terraform.RunTerraformCommand(t, terraformOptions, "test")
+}
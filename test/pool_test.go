@@ -0,0 +1,99 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countPlanned returns how many planned resource instances share address,
+// e.g. "module.compute.aws_instance.inference" matches its [0] and [1].
+func countPlanned(plan *terraform.PlanStruct, address string) int {
+	n := 0
+	for addr := range plan.ResourcePlannedValuesMap {
+		if addr == address || strings.HasPrefix(addr, address+"[") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestInferencePoolTopology(t *testing.T) {
+	t.Parallel()
+
+	endpoint := localstackEndpoint(t)
+
+	for _, replicas := range []int{1, 3} {
+		replicas := replicas
+		t.Run(fmt.Sprintf("replicas=%d", replicas), func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := localstackOptions(t, endpoint, "us-west-1", map[string]interface{}{
+				"replica_count": replicas,
+			})
+			terraformOptions.PlanFilePath = filepath.Join(terraformOptions.TerraformDir, "pool.tfplan")
+			plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+			// One OpenWebUI instance relaying to N Ollama instances
+			assert.Equal(t, 1, countPlanned(plan, "module.compute.aws_instance.app"))
+			assert.Equal(t, replicas, countPlanned(plan, "module.compute.aws_instance.inference"))
+			assert.Equal(t, replicas, countPlanned(plan, "module.compute.aws_lb_target_group_attachment.inference"))
+			for i := 0; i < replicas; i++ {
+				terraform.RequirePlannedValuesMapKeyExists(t, plan, fmt.Sprintf("module.compute.aws_instance.inference[%d]", i))
+			}
+
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.compute.aws_lb.inference[0]")
+			lb := plan.ResourcePlannedValuesMap["module.compute.aws_lb.inference[0]"].AttributeValues
+			assert.Equal(t, true, lb["internal"], "the pool must not be reachable from the internet")
+			assert.Equal(t, "network", lb["load_balancer_type"])
+			assert.Equal(t, 0, countPlanned(plan, "module.network.aws_subnet.secondary"), "an NLB fits in one subnet")
+
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.compute.aws_lb_listener.inference[0]")
+			listener := plan.ResourcePlannedValuesMap["module.compute.aws_lb_listener.inference[0]"].AttributeValues
+			assert.EqualValues(t, 11434, listener["port"])
+			assert.Equal(t, "TCP", listener["protocol"])
+
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.compute.aws_lb_target_group.inference[0]")
+			targets := plan.ResourcePlannedValuesMap["module.compute.aws_lb_target_group.inference[0]"].AttributeValues
+			assert.EqualValues(t, 11434, targets["port"])
+			healthCheck := targets["health_check"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "/api/tags", healthCheck["path"])
+
+			// Pool nodes accept Ollama traffic from inside the VPC only
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.compute.aws_security_group.inference[0]")
+			sg := plan.ResourcePlannedValuesMap["module.compute.aws_security_group.inference[0]"].AttributeValues
+			for _, rule := range sg["ingress"].([]interface{}) {
+				ingress := rule.(map[string]interface{})
+				if ingress["from_port"] == float64(11434) {
+					assert.Equal(t, []interface{}{"10.0.0.0/16"}, ingress["cidr_blocks"])
+				}
+			}
+		})
+	}
+}
+
+func TestInferencePoolApplicationLoadBalancer(t *testing.T) {
+	t.Parallel()
+
+	endpoint := localstackEndpoint(t)
+	terraformOptions := localstackOptions(t, endpoint, "us-west-1", map[string]interface{}{
+		"replica_count":             2,
+		"inference_lb_type":         "application",
+		"inference_sticky_sessions": true,
+	})
+	terraformOptions.PlanFilePath = filepath.Join(terraformOptions.TerraformDir, "pool.tfplan")
+	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+	require.Equal(t, 1, countPlanned(plan, "module.network.aws_subnet.secondary"), "an ALB needs a second availability zone")
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.compute.aws_lb_target_group.inference[0]")
+	targets := plan.ResourcePlannedValuesMap["module.compute.aws_lb_target_group.inference[0]"].AttributeValues
+	assert.Equal(t, "HTTP", targets["protocol"])
+	stickiness := targets["stickiness"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, stickiness["enabled"])
+	assert.Equal(t, "lb_cookie", stickiness["type"])
+}
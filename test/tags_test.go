@@ -0,0 +1,93 @@
+package test
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResourceTags plans every optional feature and checks that each
+// taggable resource, recognized by its tags_all attribute, carries the
+// environment, cost-allocation and extra tags under its own Name tag.
+func TestResourceTags(t *testing.T) {
+	t.Parallel()
+
+	endpoint := localstackEndpoint(t)
+
+	want := map[string]interface{}{
+		"Environment": "staging",
+		"Deployment":  "ds-tagtest",
+		"ManagedBy":   "terraform",
+		"Owner":       "ml-platform",
+		"CostCenter":  "cc-1234",
+		"Team":        "inference",
+	}
+
+	for name, vars := range map[string]map[string]interface{}{
+		"instance": {
+			"create_eip":                true,
+			"create_model_cache_bucket": true,
+			"enable_api_proxy":          true,
+			"model_volume_size":         100,
+		},
+		"autoscaling": {
+			"enable_autoscaling": true,
+			"create_eip":         true,
+			"model_volume_size":  100,
+		},
+		// The pool excludes autoscaling and a model volume
+		"inference pool": {
+			"replica_count":             2,
+			"inference_lb_type":         "application",
+			"create_model_cache_bucket": true,
+		},
+	} {
+		vars := vars
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			vars["name_prefix"] = "ds-tagtest"
+			vars["environment"] = "staging"
+			vars["owner"] = "ml-platform"
+			vars["cost_center"] = "cc-1234"
+			vars["tags"] = map[string]string{"Team": "inference"}
+
+			terraformOptions := localstackOptions(t, endpoint, "us-west-1", vars)
+			terraformOptions.PlanFilePath = filepath.Join(terraformOptions.TerraformDir, "tags.tfplan")
+			plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+			var tagged []string
+			for address, resource := range plan.ResourcePlannedValuesMap {
+				if _, taggable := resource.AttributeValues["tags_all"]; !taggable {
+					continue
+				}
+				tagged = append(tagged, address)
+
+				tags, ok := resource.AttributeValues["tags"].(map[string]interface{})
+				require.True(t, ok, "%s has no tags", address)
+				for key, value := range want {
+					assert.Equal(t, value, tags[key], "%s tag %s", address, key)
+				}
+				assert.NotEmpty(t, tags["Name"], "%s has no Name tag", address)
+			}
+			sort.Strings(tagged)
+			t.Logf("checked tags of %v", tagged)
+			require.NotEmpty(t, tagged)
+
+			if asg, ok := plan.ResourcePlannedValuesMap["module.compute.aws_autoscaling_group.app[0]"]; ok {
+				tags := map[string]interface{}{}
+				for _, tag := range asg.AttributeValues["tag"].([]interface{}) {
+					tag := tag.(map[string]interface{})
+					tags[tag["key"].(string)] = tag["value"]
+				}
+				for key, value := range want {
+					assert.Equal(t, value, tags[key], "autoscaling group tag %s", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+# Auto Scaling group mode and the Elastic IP that survives replacement.
+
+mock_provider "aws" {
+  mock_resource "aws_eip" {
+    defaults = {
+      allocation_id = "eipalloc-0a1b2c3d"
+      public_ip     = "198.51.100.7"
+    }
+  }
+}
+
+variables {
+  name_prefix               = "ds-0a1b2c3d"
+  region                    = "us-west-1"
+  ami_id                    = "ami-0735c191cf914754d"
+  instance_type             = "r6i.metal"
+  ssh_public_key            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKeyUsedOnlyByTerraformTestsXXXXXXXXXXXX terraform-test"
+  vpc_id                    = "vpc-0a1b2c3d"
+  vpc_cidr_block            = "10.0.0.0/16"
+  subnet_id                 = "subnet-0a1b2c3d"
+  availability_zone         = "us-west-1b"
+  instance_profile_name     = "ds-0a1b2c3d-profile"
+  log_group_name            = "/ds-0a1b2c3d/logs"
+  app_log_stream            = "ds-0a1b2c3d-stream"
+  model_pull_log_group_name = "/ds-0a1b2c3d/model-pull"
+  model_pull_stream         = "ds-0a1b2c3d-stream-model-pull"
+  github_token              = "test-token"
+}
+
+run "standalone_instance_by_default" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  assert {
+    condition     = length(aws_instance.app) == 1 && length(aws_autoscaling_group.app) == 0 && length(aws_launch_template.app) == 0
+    error_message = "A standalone instance should be created by default."
+  }
+}
+
+run "single_instance_group" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_autoscaling = true
+  }
+
+  assert {
+    condition     = length(aws_instance.app) == 0
+    error_message = "The standalone instance should be replaced by the ASG."
+  }
+
+  assert {
+    condition     = aws_autoscaling_group.app[0].min_size == 1 && aws_autoscaling_group.app[0].max_size == 1
+    error_message = "The ASG should run exactly one instance by default."
+  }
+
+  assert {
+    condition     = aws_autoscaling_group.app[0].health_check_type == "EC2"
+    error_message = "EC2 status checks should drive replacement by default."
+  }
+
+  assert {
+    condition     = one(aws_autoscaling_group.app[0].initial_lifecycle_hook).lifecycle_transition == "autoscaling:EC2_INSTANCE_LAUNCHING"
+    error_message = "New instances should wait on a launch lifecycle hook."
+  }
+
+  assert {
+    condition     = one(one(aws_launch_template.app[0].block_device_mappings).ebs).encrypted == "true"
+    error_message = "The launch template should encrypt the root volume."
+  }
+
+  assert {
+    condition     = one(aws_launch_template.app[0].metadata_options).http_tokens == "required"
+    error_message = "The launch template should require IMDSv2."
+  }
+
+  assert {
+    condition     = one(aws_launch_template.app[0].iam_instance_profile).name == "ds-0a1b2c3d-profile"
+    error_message = "The launch template should carry the instance profile."
+  }
+
+  assert {
+    condition     = one(aws_launch_template.app[0].network_interfaces).security_groups == toset([aws_security_group.app.id])
+    error_message = "The launch template should carry the security group."
+  }
+
+  assert {
+    condition     = one(aws_launch_template.app[0].block_device_mappings).device_name == "/dev/sda1"
+    error_message = "The launch template should size the AMI's root device."
+  }
+
+  assert {
+    condition     = aws_launch_template.app[0].user_data == module.bootstrap.user_data
+    error_message = "The launch template should carry the bootstrap user data."
+  }
+
+  assert {
+    condition     = join(",", module.bootstrap.stage_names) == "cloudwatch,packages,docker,claim,app,models"
+    error_message = "The claim stage should run between docker and app."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["claim"], "complete_launch_hook CONTINUE")
+    error_message = "The claim stage should complete the lifecycle hook."
+  }
+
+  assert {
+    condition     = output.autoscaling_group_name == "ds-0a1b2c3d-asg" && output.instance_id == ""
+    error_message = "Outputs should expose the ASG name instead of an instance ID."
+  }
+
+  assert {
+    condition     = output.public_ip == ""
+    error_message = "No address is known without an Elastic IP."
+  }
+}
+
+run "group_reclaims_volume_and_eip" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_autoscaling = true
+    create_eip         = true
+    model_volume_size  = 1000
+  }
+
+  assert {
+    condition     = length(aws_volume_attachment.models) == 0 && length(aws_eip_association.app) == 0
+    error_message = "The instance should claim the volume and address itself."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["claim"], "attach-volume --volume-id \"${aws_ebs_volume.models[0].id}\"")
+    error_message = "The claim stage should attach the persistent model volume."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["claim"], "associate-address --allocation-id \"eipalloc-0a1b2c3d\"")
+    error_message = "The claim stage should re-associate the Elastic IP."
+  }
+
+  assert {
+    condition     = output.public_ip == "198.51.100.7" && output.eip_allocation_id == "eipalloc-0a1b2c3d"
+    error_message = "Outputs should use the Elastic IP."
+  }
+}
+
+run "standalone_instance_with_eip" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    create_eip = true
+  }
+
+  assert {
+    condition     = aws_eip_association.app[0].instance_id == aws_instance.app[0].id
+    error_message = "Terraform should associate the Elastic IP with the standalone instance."
+  }
+
+  assert {
+    condition     = !contains(module.bootstrap.stage_names, "claim")
+    error_message = "The standalone instance should not associate the address itself."
+  }
+}
+
+run "claimed_resources_limit_group_size" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_autoscaling = true
+    create_eip         = true
+    asg_max_size       = 2
+  }
+
+  expect_failures = [aws_autoscaling_group.app]
+}
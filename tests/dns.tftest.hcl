@@ -0,0 +1,124 @@
+# Stable endpoints: Elastic IP and Route 53 records.
+
+mock_provider "aws" {
+  mock_resource "aws_instance" {
+    defaults = {
+      public_ip = "203.0.113.10"
+    }
+  }
+
+  mock_resource "aws_eip" {
+    defaults = {
+      allocation_id = "eipalloc-0a1b2c3d"
+      public_ip     = "198.51.100.7"
+    }
+  }
+
+  mock_resource "aws_route53_record" {
+    defaults = {
+      fqdn = "ollama.example.com"
+    }
+  }
+}
+
+variables {
+  name_prefix               = "ds-0a1b2c3d"
+  region                    = "us-west-1"
+  ami_id                    = "ami-0735c191cf914754d"
+  instance_type             = "r6i.metal"
+  ssh_public_key            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKeyUsedOnlyByTerraformTestsXXXXXXXXXXXX terraform-test"
+  vpc_id                    = "vpc-0a1b2c3d"
+  vpc_cidr_block            = "10.0.0.0/16"
+  subnet_id                 = "subnet-0a1b2c3d"
+  availability_zone         = "us-west-1b"
+  instance_profile_name     = "ds-0a1b2c3d-profile"
+  log_group_name            = "/ds-0a1b2c3d/logs"
+  app_log_stream            = "ds-0a1b2c3d-stream"
+  model_pull_log_group_name = "/ds-0a1b2c3d/model-pull"
+  model_pull_stream         = "ds-0a1b2c3d-stream-model-pull"
+  github_token              = "test-token"
+}
+
+run "no_records_by_default" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  assert {
+    condition     = length(aws_route53_record.app) == 0 && length(aws_eip.app) == 0
+    error_message = "DNS and Elastic IP should be opt-in."
+  }
+
+  assert {
+    condition     = output.public_ip == "203.0.113.10"
+    error_message = "public_ip should fall back to the instance's address."
+  }
+}
+
+run "hostname_with_eip" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    create_eip      = true
+    route53_zone_id = "Z0123456789ABCDEFGHIJ"
+    dns_hostname    = "ollama.example.com"
+  }
+
+  assert {
+    condition     = aws_route53_record.app["ollama.example.com"].records == toset(["198.51.100.7"])
+    error_message = "The record should point at the Elastic IP."
+  }
+
+  assert {
+    condition     = one(output.dns_records) == "ollama.example.com"
+    error_message = "dns_records should list the record's FQDN."
+  }
+
+  assert {
+    condition     = output.public_ip == "198.51.100.7"
+    error_message = "public_ip should report the Elastic IP."
+  }
+}
+
+run "proxy_domain_gets_its_own_record" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    route53_zone_id    = "Z0123456789ABCDEFGHIJ"
+    dns_hostname       = "chat.example.com"
+    enable_api_proxy   = true
+    api_proxy_tls_mode = "acme"
+    api_proxy_domain   = "api.example.com"
+  }
+
+  assert {
+    condition     = toset(keys(aws_route53_record.app)) == toset(["chat.example.com", "api.example.com"])
+    error_message = "Both the instance and proxy hostnames should get records."
+  }
+}
+
+run "autoscaling_records_need_eip" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_autoscaling = true
+    route53_zone_id    = "Z0123456789ABCDEFGHIJ"
+    dns_hostname       = "ollama.example.com"
+  }
+
+  expect_failures = [aws_route53_record.app]
+}
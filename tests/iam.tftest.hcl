@@ -0,0 +1,151 @@
+# Shape of the per-feature instance role policy documents of the iam module.
+
+mock_provider "aws" {
+  mock_data "aws_partition" {
+    defaults = {
+      partition = "aws"
+    }
+  }
+
+  mock_data "aws_caller_identity" {
+    defaults = {
+      account_id = "123456789012"
+    }
+  }
+
+  mock_data "aws_region" {
+    defaults = {
+      name = "us-west-1"
+    }
+  }
+}
+
+variables {
+  name_prefix    = "ds-0a1b2c3d"
+  log_group_arns = ["arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/logs", "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/model-pull"]
+}
+
+run "role_trusts_ec2_only" {
+  command = apply
+
+  module {
+    source = "./modules/iam"
+  }
+
+  assert {
+    condition     = one(data.aws_iam_policy_document.assume_role.statement[0].principals).identifiers == toset(["ec2.amazonaws.com"])
+    error_message = "The instance role should only be assumable by EC2."
+  }
+}
+
+run "logs_only_by_default" {
+  command = apply
+
+  module {
+    source = "./modules/iam"
+  }
+
+  assert {
+    condition     = length(data.aws_iam_policy_document.api_proxy) == 0
+    error_message = "Without the proxy no secret statement should be generated."
+  }
+
+  assert {
+    condition = toset(data.aws_iam_policy_document.logs.statement[0].resources) == toset([
+      "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/logs",
+      "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/logs:*",
+      "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/model-pull",
+      "arn:aws:logs:us-west-1:123456789012:log-group:/ds-0a1b2c3d/model-pull:*",
+    ])
+    error_message = "Log permissions should be scoped to the deployment's log groups."
+  }
+
+  assert {
+    condition     = alltrue([for action in data.aws_iam_policy_document.logs.statement[0].actions : startswith(action, "logs:")])
+    error_message = "The log statement should only contain logs: actions."
+  }
+
+  assert {
+    condition     = length(data.aws_iam_policy_document.autoscaling) == 0
+    error_message = "The role needs no lifecycle permissions without an ASG."
+  }
+}
+
+run "proxy_secret_read_access" {
+  command = apply
+
+  module {
+    source = "./modules/iam"
+  }
+
+  variables {
+    enable_api_proxy     = true
+    api_proxy_secret_arn = "arn:aws:secretsmanager:us-west-1:123456789012:secret:ds-0a1b2c3d-ollama-api-token-AbCdEf"
+  }
+
+  assert {
+    condition     = toset(data.aws_iam_policy_document.api_proxy[0].statement[0].actions) == toset(["secretsmanager:GetSecretValue"])
+    error_message = "The proxy should only be granted GetSecretValue."
+  }
+
+  assert {
+    condition     = toset(data.aws_iam_policy_document.api_proxy[0].statement[0].resources) == toset([var.api_proxy_secret_arn])
+    error_message = "Secret access should be scoped to the proxy secret."
+  }
+}
+
+run "model_cache_scoped_to_prefix" {
+  command = apply
+
+  module {
+    source = "./modules/iam"
+  }
+
+  variables {
+    enable_model_cache = true
+    model_cache_bucket = "shared-model-cache"
+    model_cache_prefix = "ollama/models"
+  }
+
+  assert {
+    condition     = toset(data.aws_iam_policy_document.model_cache[0].statement[1].actions) == toset(["s3:GetObject", "s3:PutObject"])
+    error_message = "The model cache should only be granted object reads and writes."
+  }
+
+  assert {
+    condition     = endswith(one(data.aws_iam_policy_document.model_cache[0].statement[1].resources), ":s3:::shared-model-cache/ollama/models/*")
+    error_message = "Object access should be scoped to the cache prefix."
+  }
+}
+
+run "autoscaling_claims_own_resources" {
+  command = plan
+
+  module {
+    source = "./modules/iam"
+  }
+
+  variables {
+    enable_autoscaling     = true
+    autoscaling_group_name = "ds-0a1b2c3d-asg"
+    claim_model_volume     = true
+    model_volume_arn       = "arn:aws:ec2:us-west-1:123456789012:volume/vol-0a1b2c3d"
+    claim_eip              = true
+    eip_allocation_id      = "eipalloc-0a1b2c3d"
+  }
+
+  assert {
+    condition     = toset([for s in data.aws_iam_policy_document.autoscaling[0].statement : s.sid]) == toset(["CompleteLaunchHook", "ClaimFromOwnInstances", "AttachModelVolume", "AssociateElasticIp"])
+    error_message = "The role should be allowed to claim exactly the deployment's volume and address."
+  }
+
+  assert {
+    condition     = toset(data.aws_iam_policy_document.autoscaling[0].statement[0].resources) == toset(["arn:aws:autoscaling:us-west-1:123456789012:autoScalingGroup:*:autoScalingGroupName/ds-0a1b2c3d-asg"])
+    error_message = "Lifecycle actions should be scoped to the deployment's group."
+  }
+
+  assert {
+    condition     = one(one(data.aws_iam_policy_document.autoscaling[0].statement[1].condition).values) == "ds-0a1b2c3d-instance"
+    error_message = "Only the deployment's own instances should be able to claim resources."
+  }
+}
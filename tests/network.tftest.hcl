@@ -0,0 +1,109 @@
+# VPC, subnets and routing of the network module.
+
+mock_provider "aws" {
+  mock_data "aws_availability_zones" {
+    defaults = {
+      names = ["us-west-1a", "us-west-1b"]
+    }
+  }
+}
+
+variables {
+  name_prefix = "ds-0a1b2c3d"
+}
+
+run "first_zone_by_default" {
+  command = plan
+
+  module {
+    source = "./modules/network"
+  }
+
+  assert {
+    condition     = aws_vpc.main.cidr_block == "10.0.0.0/16" && aws_subnet.public.cidr_block == "10.0.1.0/24"
+    error_message = "Unexpected VPC or subnet CIDR."
+  }
+
+  assert {
+    condition     = aws_subnet.public.availability_zone == "us-west-1a"
+    error_message = "The public subnet should default to the region's first availability zone."
+  }
+
+  assert {
+    condition     = length(aws_subnet.secondary) == 0
+    error_message = "No second subnet should be created unless requested."
+  }
+
+  assert {
+    condition     = one(aws_route_table.main.route).cidr_block == "0.0.0.0/0"
+    error_message = "The public subnet should route to the internet gateway."
+  }
+}
+
+run "pinned_zone" {
+  command = plan
+
+  module {
+    source = "./modules/network"
+  }
+
+  variables {
+    availability_zone = "us-west-1b"
+  }
+
+  assert {
+    condition     = aws_subnet.public.availability_zone == "us-west-1b"
+    error_message = "availability_zone should place the public subnet."
+  }
+
+  assert {
+    condition     = length(data.aws_availability_zones.available) == 0
+    error_message = "A pinned zone should skip the zone lookup."
+  }
+}
+
+run "secondary_subnet_in_another_zone" {
+  command = apply
+
+  module {
+    source = "./modules/network"
+  }
+
+  variables {
+    availability_zone = "us-west-1b"
+    secondary_subnet  = true
+  }
+
+  assert {
+    condition     = aws_subnet.secondary[0].availability_zone == "us-west-1a" && aws_subnet.secondary[0].cidr_block == "10.0.2.0/24"
+    error_message = "The second subnet should use another availability zone."
+  }
+
+  assert {
+    condition     = one(output.secondary_subnet_ids) == aws_subnet.secondary[0].id
+    error_message = "The second subnet should be exported once it is routed."
+  }
+}
+
+run "subnets_follow_vpc_cidr_block" {
+  command = plan
+
+  module {
+    source = "./modules/network"
+  }
+
+  variables {
+    vpc_cidr_block   = "172.20.0.0/16"
+    secondary_subnet = true
+  }
+
+  assert {
+    condition     = aws_vpc.main.cidr_block == "172.20.0.0/16"
+    error_message = "The VPC should use vpc_cidr_block."
+  }
+
+  assert {
+    condition     = aws_subnet.public.cidr_block == "172.20.1.0/24" && aws_subnet.secondary[0].cidr_block == "172.20.2.0/24"
+    error_message = "The subnets should be carved out of vpc_cidr_block."
+  }
+}
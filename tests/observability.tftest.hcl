@@ -0,0 +1,113 @@
+# Log groups, saved Logs Insights queries, error metric filters and alarms of
+# the observability module.
+
+mock_provider "aws" {
+  mock_resource "aws_sns_topic" {
+    defaults = {
+      arn = "arn:aws:sns:us-west-1:123456789012:ds-0a1b2c3d-notifications"
+    }
+  }
+}
+
+variables {
+  name_prefix = "ds-0a1b2c3d"
+}
+
+run "saved_queries" {
+  command = apply
+
+  module {
+    source = "./modules/observability"
+  }
+
+  assert {
+    condition     = length(aws_cloudwatch_query_definition.this) == 6
+    error_message = "Unexpected number of saved queries."
+  }
+
+  assert {
+    condition     = alltrue([for query in aws_cloudwatch_query_definition.this : toset(query.log_group_names) == toset(["/ds-0a1b2c3d/logs", "/ds-0a1b2c3d/model-pull"])])
+    error_message = "Every saved query should search both of the deployment's log groups."
+  }
+
+  assert {
+    condition     = strcontains(aws_cloudwatch_query_definition.this["Errors"].query_string, "filter level = \"error\"")
+    error_message = "The Errors query should select error-level events."
+  }
+
+  assert {
+    condition     = strcontains(aws_cloudwatch_query_definition.this["Model pull results"].query_string, "event = \"model_pull_result\"")
+    error_message = "The model pull query should select the model stage's result events."
+  }
+
+  assert {
+    condition     = toset(output.query_definition_names) == toset([for name in keys(aws_cloudwatch_query_definition.this) : "ds-0a1b2c3d/${name}"])
+    error_message = "Saved queries should be grouped in a folder named after the deployment."
+  }
+}
+
+run "retention_per_stream_class" {
+  command = plan
+
+  module {
+    source = "./modules/observability"
+  }
+
+  variables {
+    log_retention_days = {
+      model_pull = 7
+    }
+  }
+
+  assert {
+    condition     = aws_cloudwatch_log_group.app_logs.retention_in_days == 30 && aws_cloudwatch_log_group.model_pull_logs.retention_in_days == 7
+    error_message = "Each class of stream should keep its own retention, defaulting to 30 days."
+  }
+
+  assert {
+    condition     = aws_cloudwatch_log_stream.app_log_stream.log_group_name == "/ds-0a1b2c3d/logs" && aws_cloudwatch_log_stream.model_pull_stream.log_group_name == "/ds-0a1b2c3d/model-pull"
+    error_message = "Each stream should live in its class's log group."
+  }
+}
+
+run "error_alarms" {
+  command = apply
+
+  module {
+    source = "./modules/observability"
+  }
+
+  variables {
+    notification_emails = ["ops@example.com"]
+  }
+
+  assert {
+    condition     = aws_cloudwatch_log_metric_filter.errors["DockerComposeFailures"].log_group_name == "/ds-0a1b2c3d/logs" && aws_cloudwatch_log_metric_filter.errors["ModelPullFailures"].log_group_name == "/ds-0a1b2c3d/model-pull"
+    error_message = "Each metric filter should read its stream class's log group."
+  }
+
+  assert {
+    condition     = strcontains(aws_cloudwatch_log_metric_filter.errors["ModelPullFailures"].pattern, "$.message = \"Failed to pull model after *\"")
+    error_message = "The model pull failure filter should match the final pull error."
+  }
+
+  assert {
+    condition     = alltrue([for filter in aws_cloudwatch_log_metric_filter.errors : filter.metric_transformation[0].dimensions["Deployment"] == "$.deployment_id"])
+    error_message = "Error metrics should be counted per deployment."
+  }
+
+  assert {
+    condition     = alltrue([for alarm in aws_cloudwatch_metric_alarm.errors : alarm.dimensions["Deployment"] == "ds-0a1b2c3d" && alarm.threshold == 1 && alarm.period == 300])
+    error_message = "Alarms should watch this deployment's error counts over five minutes."
+  }
+
+  assert {
+    condition     = alltrue([for alarm in aws_cloudwatch_metric_alarm.errors : alarm.alarm_actions == toset(["arn:aws:sns:us-west-1:123456789012:ds-0a1b2c3d-notifications"])])
+    error_message = "Every alarm should notify the deployment's topic."
+  }
+
+  assert {
+    condition     = aws_sns_topic_subscription.email["ops@example.com"].protocol == "email"
+    error_message = "Notification emails should be subscribed to the topic."
+  }
+}
@@ -0,0 +1,138 @@
+# Inference pool mode: OpenWebUI on one instance relaying to an internal
+# load balancer in front of replica_count Ollama instances.
+
+mock_provider "aws" {
+  mock_resource "aws_lb" {
+    defaults = {
+      dns_name = "internal-ds-0a1b2c3d-ollama.us-west-1.elb.amazonaws.com"
+    }
+  }
+}
+
+variables {
+  name_prefix               = "ds-0a1b2c3d"
+  region                    = "us-west-1"
+  ami_id                    = "ami-0735c191cf914754d"
+  instance_type             = "r6i.metal"
+  ssh_public_key            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKeyUsedOnlyByTerraformTestsXXXXXXXXXXXX terraform-test"
+  vpc_id                    = "vpc-0a1b2c3d"
+  vpc_cidr_block            = "10.0.0.0/16"
+  subnet_id                 = "subnet-0a1b2c3d"
+  availability_zone         = "us-west-1b"
+  instance_profile_name     = "ds-0a1b2c3d-profile"
+  log_group_name            = "/ds-0a1b2c3d/logs"
+  app_log_stream            = "ds-0a1b2c3d-stream"
+  model_pull_log_group_name = "/ds-0a1b2c3d/model-pull"
+  model_pull_stream         = "ds-0a1b2c3d-stream-model-pull"
+  github_token              = "test-token"
+}
+
+run "single_host_by_default" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  assert {
+    condition     = length(aws_instance.inference) == 0 && length(aws_lb.inference) == 0
+    error_message = "No pool should be created by default."
+  }
+
+  assert {
+    condition     = contains(module.bootstrap.stage_names, "models")
+    error_message = "The single host should pull the model itself."
+  }
+}
+
+run "web_host_relays_to_pool" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    replica_count = 3
+  }
+
+  assert {
+    condition     = length(aws_instance.app) == 1 && length(aws_instance.inference) == 3
+    error_message = "OpenWebUI should run once next to three inference instances."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["app"], "TCP:internal-ds-0a1b2c3d-ollama.us-west-1.elb.amazonaws.com:11434")
+    error_message = "The web host should relay Ollama traffic to the load balancer."
+  }
+
+  assert {
+    condition     = !contains(module.bootstrap.stage_names, "models")
+    error_message = "The web host should not pull models."
+  }
+
+  assert {
+    condition     = strcontains(module.inference_bootstrap[0].stages["app"], "docker compose up -d ollama")
+    error_message = "Inference instances should only run Ollama."
+  }
+
+  assert {
+    condition     = contains(module.inference_bootstrap[0].stage_names, "models") && aws_instance.inference[0].user_data_base64 == module.inference_bootstrap[0].user_data
+    error_message = "Inference instances should pull models with their own bootstrap."
+  }
+
+  assert {
+    condition     = toset(aws_lb_target_group_attachment.inference[*].target_id) == toset(aws_instance.inference[*].id)
+    error_message = "Every inference instance should be registered with the load balancer."
+  }
+
+  assert {
+    condition     = output.inference_lb_dns_name == "internal-ds-0a1b2c3d-ollama.us-west-1.elb.amazonaws.com"
+    error_message = "inference_lb_dns_name should name the load balancer."
+  }
+}
+
+run "application_load_balancer" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    replica_count             = 2
+    inference_lb_type         = "application"
+    inference_sticky_sessions = true
+    secondary_subnet_ids      = ["subnet-0e1f2a3b"]
+  }
+
+  assert {
+    condition     = toset(aws_lb.inference[0].subnets) == toset(["subnet-0a1b2c3d", "subnet-0e1f2a3b"])
+    error_message = "The ALB should span the public and the secondary subnet."
+  }
+
+  assert {
+    condition     = aws_lb.inference[0].internal && aws_lb.inference[0].idle_timeout == 3600
+    error_message = "The ALB should be internal with a long idle timeout."
+  }
+
+  assert {
+    condition     = one(aws_lb_target_group.inference[0].stickiness).type == "lb_cookie"
+    error_message = "ALB stickiness should use a load balancer cookie."
+  }
+}
+
+run "pool_excludes_autoscaling" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    replica_count      = 2
+    enable_autoscaling = true
+  }
+
+  expect_failures = [aws_instance.inference]
+}
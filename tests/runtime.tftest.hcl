@@ -0,0 +1,85 @@
+# Ollama runtime settings and warm-up rendered into the instance user data
+# by the compute module.
+
+mock_provider "aws" {}
+
+variables {
+  name_prefix               = "ds-0a1b2c3d"
+  region                    = "us-west-1"
+  ami_id                    = "ami-0735c191cf914754d"
+  instance_type             = "r6i.metal"
+  ssh_public_key            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKeyUsedOnlyByTerraformTestsXXXXXXXXXXXX terraform-test"
+  vpc_id                    = "vpc-0a1b2c3d"
+  vpc_cidr_block            = "10.0.0.0/16"
+  subnet_id                 = "subnet-0a1b2c3d"
+  availability_zone         = "us-west-1b"
+  instance_profile_name     = "ds-0a1b2c3d-profile"
+  log_group_name            = "/ds-0a1b2c3d/logs"
+  app_log_stream            = "ds-0a1b2c3d-stream"
+  model_pull_log_group_name = "/ds-0a1b2c3d/model-pull"
+  model_pull_stream         = "ds-0a1b2c3d-stream-model-pull"
+  github_token              = "test-token"
+}
+
+run "nproc_threads_by_default" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["models"], "NUM_THREADS=$(nproc)")
+    error_message = "The thread count should default to the number of cores."
+  }
+
+  assert {
+    condition     = !strcontains(module.bootstrap.stages["models"], "warm_up \"")
+    error_message = "No model should be warmed up unless flagged."
+  }
+}
+
+run "runtime_settings_in_user_data" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    ollama_keep_alive    = "2h"
+    ollama_num_parallel  = 4
+    ollama_num_threads   = 48
+    ollama_warmup_models = ["deepseek-r1:671b"]
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["app"], "OLLAMA_KEEP_ALIVE: \"2h\"") && strcontains(module.bootstrap.stages["app"], "OLLAMA_NUM_PARALLEL: \"4\"")
+    error_message = "Ollama runtime settings should be rendered into the container environment."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["models"], "NUM_THREADS=48")
+    error_message = "An explicit thread count should replace the nproc default."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["models"], "warm_up \"deepseek-r1:671b\"")
+    error_message = "Flagged models should be warmed up before the deployment is reported ready."
+  }
+}
+
+run "warmup_must_fit_loaded_models" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    ollama_warmup_models     = ["deepseek-r1:671b", "qwen2.5:0.5b"]
+    ollama_max_loaded_models = 1
+  }
+
+  expect_failures = [aws_instance.app]
+}
@@ -0,0 +1,129 @@
+# Security group rules of the compute module with and without the Ollama
+# API proxy, and the SSH key pair.
+
+mock_provider "aws" {}
+
+variables {
+  name_prefix               = "ds-0a1b2c3d"
+  region                    = "us-west-1"
+  ami_id                    = "ami-0735c191cf914754d"
+  instance_type             = "r6i.metal"
+  ssh_public_key            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKeyUsedOnlyByTerraformTestsXXXXXXXXXXXX terraform-test"
+  vpc_id                    = "vpc-0a1b2c3d"
+  vpc_cidr_block            = "10.0.0.0/16"
+  subnet_id                 = "subnet-0a1b2c3d"
+  availability_zone         = "us-west-1b"
+  instance_profile_name     = "ds-0a1b2c3d-profile"
+  log_group_name            = "/ds-0a1b2c3d/logs"
+  app_log_stream            = "ds-0a1b2c3d-stream"
+  model_pull_log_group_name = "/ds-0a1b2c3d/model-pull"
+  model_pull_stream         = "ds-0a1b2c3d-stream-model-pull"
+  github_token              = "test-token"
+}
+
+run "direct_ollama_access" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  assert {
+    condition     = sort([for rule in aws_security_group.app.ingress : tostring(rule.from_port)]) == sort(["22", "8080", "11434"])
+    error_message = "Without the proxy the security group should open SSH, OpenWebUI and Ollama."
+  }
+
+  assert {
+    condition     = alltrue([for rule in aws_security_group.app.ingress : rule.from_port == rule.to_port && rule.protocol == "tcp"])
+    error_message = "Ingress rules should each open a single TCP port."
+  }
+}
+
+run "restricted_source_cidrs" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    ssh_cidr_blocks     = ["203.0.113.0/24"]
+    ingress_cidr_blocks = ["198.51.100.0/24", "192.0.2.10/32"]
+  }
+
+  assert {
+    condition     = alltrue([for rule in aws_security_group.app.ingress : toset(rule.cidr_blocks) == toset(["203.0.113.0/24"]) if rule.from_port == 22])
+    error_message = "SSH should only be open to ssh_cidr_blocks."
+  }
+
+  assert {
+    condition     = alltrue([for rule in aws_security_group.app.ingress : toset(rule.cidr_blocks) == toset(["198.51.100.0/24", "192.0.2.10/32"]) if rule.from_port != 22])
+    error_message = "OpenWebUI and Ollama should only be open to ingress_cidr_blocks."
+  }
+}
+
+run "proxy_replaces_ollama_port" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_api_proxy = true
+    api_proxy_port   = 8443
+  }
+
+  assert {
+    condition     = sort([for rule in aws_security_group.app.ingress : tostring(rule.from_port)]) == sort(["22", "8080", "8443"])
+    error_message = "With the proxy enabled only the proxy port should replace 11434."
+  }
+}
+
+run "acme_opens_http_challenge_port" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_api_proxy   = true
+    api_proxy_tls_mode = "acme"
+    api_proxy_domain   = "ollama.example.com"
+  }
+
+  assert {
+    condition     = contains([for rule in aws_security_group.app.ingress : rule.from_port], 80)
+    error_message = "ACME mode should open port 80 for HTTP-01 challenges."
+  }
+}
+
+run "acme_requires_domain" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    enable_api_proxy   = true
+    api_proxy_tls_mode = "acme"
+  }
+
+  expect_failures = [aws_instance.app]
+}
+
+run "requires_ssh_public_key" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    ssh_public_key = ""
+  }
+
+  expect_failures = [aws_key_pair.app]
+}
@@ -0,0 +1,144 @@
+# Dedicated model volume, optionally restored from a golden snapshot.
+
+mock_provider "aws" {
+  mock_data "aws_ebs_snapshot" {
+    defaults = {
+      id = "snap-0newest00000000000"
+    }
+  }
+}
+
+variables {
+  name_prefix               = "ds-0a1b2c3d"
+  region                    = "us-west-1"
+  ami_id                    = "ami-0735c191cf914754d"
+  instance_type             = "r6i.metal"
+  ssh_public_key            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFakeKeyUsedOnlyByTerraformTestsXXXXXXXXXXXX terraform-test"
+  vpc_id                    = "vpc-0a1b2c3d"
+  vpc_cidr_block            = "10.0.0.0/16"
+  subnet_id                 = "subnet-0a1b2c3d"
+  availability_zone         = "us-west-1b"
+  instance_profile_name     = "ds-0a1b2c3d-profile"
+  log_group_name            = "/ds-0a1b2c3d/logs"
+  app_log_stream            = "ds-0a1b2c3d-stream"
+  model_pull_log_group_name = "/ds-0a1b2c3d/model-pull"
+  model_pull_stream         = "ds-0a1b2c3d-stream-model-pull"
+  github_token              = "test-token"
+}
+
+run "root_volume_by_default" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  assert {
+    condition     = length(aws_ebs_volume.models) == 0 && length(aws_volume_attachment.models) == 0
+    error_message = "No model volume should be created by default."
+  }
+
+  assert {
+    condition     = !strcontains(module.bootstrap.stages["app"], "compose.models.yaml")
+    error_message = "Ollama should keep its default storage without a model volume."
+  }
+
+  assert {
+    condition     = one(aws_instance.app[0].root_block_device).encrypted
+    error_message = "The root volume should be encrypted."
+  }
+
+  assert {
+    condition     = one(aws_instance.app[0].metadata_options).http_tokens == "required"
+    error_message = "The instance should require IMDSv2."
+  }
+}
+
+run "empty_model_volume" {
+  command = apply
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    model_volume_size = 1500
+  }
+
+  assert {
+    condition     = aws_ebs_volume.models[0].size == 1500 && aws_ebs_volume.models[0].snapshot_id == null
+    error_message = "An empty volume of the requested size should be created."
+  }
+
+  assert {
+    condition     = aws_ebs_volume.models[0].encrypted && aws_ebs_volume.models[0].availability_zone == "us-west-1b"
+    error_message = "The model volume should be encrypted and live in the instance's AZ."
+  }
+
+  assert {
+    condition     = aws_volume_attachment.models[0].instance_id == aws_instance.app[0].id
+    error_message = "The model volume should be attached to the instance."
+  }
+
+  assert {
+    condition     = strcontains(module.bootstrap.stages["app"], aws_ebs_volume.models[0].id)
+    error_message = "The app stage should wait for the model volume by ID."
+  }
+}
+
+run "snapshot_by_id" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    model_volume_snapshot_id = "snap-0pinned0000000000"
+  }
+
+  assert {
+    condition     = aws_ebs_volume.models[0].snapshot_id == "snap-0pinned0000000000"
+    error_message = "The model volume should be restored from the given snapshot."
+  }
+
+  assert {
+    condition     = length(data.aws_ebs_snapshot.models) == 0
+    error_message = "A pinned snapshot ID should skip the tag lookup."
+  }
+
+  assert {
+    condition     = length(aws_ebs_fast_snapshot_restore.models) == 0
+    error_message = "Fast Snapshot Restore should be opt-in."
+  }
+}
+
+run "newest_snapshot_by_tags" {
+  command = plan
+
+  module {
+    source = "./modules/compute"
+  }
+
+  variables {
+    model_volume_snapshot_tags = {
+      Purpose = "ollama-models"
+    }
+    model_volume_fast_snapshot_restore = true
+  }
+
+  assert {
+    condition     = aws_ebs_volume.models[0].snapshot_id == "snap-0newest00000000000"
+    error_message = "The newest tagged snapshot should be used."
+  }
+
+  assert {
+    condition     = data.aws_ebs_snapshot.models[0].most_recent && contains(data.aws_ebs_snapshot.models[0].owners, "self")
+    error_message = "The lookup should pick the newest snapshot owned by this account."
+  }
+
+  assert {
+    condition     = aws_ebs_fast_snapshot_restore.models[0].snapshot_id == "snap-0newest00000000000"
+    error_message = "Fast Snapshot Restore should be enabled for the selected snapshot."
+  }
+}
@@ -1,35 +1,485 @@
-variable "aws_region" {
-  description = "AWS region to deploy the infrastructure"
+variable "name_prefix" {
+  description = "Prefix of every resource name. A unique ds-<random hex> prefix is generated when empty"
   type        = string
-  default     = "us-west-1"
+  default     = ""
+
+  validation {
+    condition     = var.name_prefix == "" || can(regex("^[a-z][a-z0-9-]{0,23}$", var.name_prefix))
+    error_message = "name_prefix must start with a lowercase letter and contain at most 24 lowercase letters, digits and hyphens."
+  }
+}
+
+variable "environment" {
+  description = "Environment tag of every resource, e.g. production or staging"
+  type        = string
+  default     = "production"
+}
+
+variable "owner" {
+  description = "Owner cost-allocation tag of every resource; omitted when empty"
+  type        = string
+  default     = ""
+}
+
+variable "cost_center" {
+  description = "CostCenter cost-allocation tag of every resource; omitted when empty"
+  type        = string
+  default     = ""
+}
+
+variable "tags" {
+  description = "Additional tags for every resource. They override the Environment, Owner and CostCenter tags"
+  type        = map(string)
+  default     = {}
 }
 
 variable "ami_id" {
   description = "AMI ID for the EC2 instance (Ubuntu 22.04 LTS)"
   type        = string
   default     = "ami-0735c191cf914754d"
+
+  validation {
+    condition     = can(regex("^ami-([0-9a-f]{8}|[0-9a-f]{17})$", var.ami_id))
+    error_message = "ami_id must be an AMI ID such as ami-0735c191cf914754d."
+  }
+}
+
+variable "root_device_name" {
+  description = "Root device name of ami_id, used to size the root volume in autoscaling mode"
+  type        = string
+  default     = "/dev/sda1"
+}
+
+variable "availability_zone" {
+  description = "Availability zone of the public subnet. The first available zone of the provider's region is used when empty; deployments created before this variable existed run in us-west-1b"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.availability_zone == "" || can(regex("^[a-z]{2}(-gov)?-[a-z]+-[0-9][a-z]$", var.availability_zone))
+    error_message = "availability_zone must be a zone name such as us-west-1b."
+  }
+}
+
+variable "vpc_cidr_block" {
+  description = "IPv4 CIDR block of the VPC. Subnets take its second and third /8-longer blocks (10.0.1.0/24 and 10.0.2.0/24 by default)"
+  type        = string
+  default     = "10.0.0.0/16"
+
+  validation {
+    condition     = can(cidrnetmask(var.vpc_cidr_block)) && can(regex("/(1[6-9]|20)$", var.vpc_cidr_block))
+    error_message = "vpc_cidr_block must be an IPv4 CIDR block between /16 and /20, such as 10.0.0.0/16."
+  }
+}
+
+variable "ssh_cidr_blocks" {
+  description = "IPv4 CIDR blocks allowed to connect over SSH"
+  type        = list(string)
+  default     = ["0.0.0.0/0"]
+
+  validation {
+    condition     = alltrue([for cidr in var.ssh_cidr_blocks : can(cidrnetmask(cidr))])
+    error_message = "ssh_cidr_blocks must contain IPv4 CIDR blocks such as 203.0.113.0/24."
+  }
+}
+
+variable "ingress_cidr_blocks" {
+  description = "IPv4 CIDR blocks allowed to reach OpenWebUI, the Ollama API and the API proxy"
+  type        = list(string)
+  default     = ["0.0.0.0/0"]
+
+  validation {
+    condition     = alltrue([for cidr in var.ingress_cidr_blocks : can(cidrnetmask(cidr))])
+    error_message = "ingress_cidr_blocks must contain IPv4 CIDR blocks such as 203.0.113.0/24."
+  }
+}
+
+variable "ssh_public_key" {
+  description = "Public SSH key (OpenSSH format) for EC2 instance access. Takes precedence over ssh_public_key_path"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.ssh_public_key == "" || can(regex("^(ssh-(ed25519|rsa)|ecdsa-sha2-nistp(256|384|521)) ", var.ssh_public_key))
+    error_message = "ssh_public_key must be an OpenSSH public key (ssh-ed25519, ssh-rsa or ecdsa-sha2-*)."
+  }
 }
 
 variable "ssh_public_key_path" {
-  description = "Path to the public SSH key for EC2 instance access"
+  description = "Path to the public SSH key for EC2 instance access, used when ssh_public_key is empty"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.ssh_public_key_path == "" || can(regex("^(ssh-(ed25519|rsa)|ecdsa-sha2-nistp(256|384|521)) ", file(var.ssh_public_key_path)))
+    error_message = "ssh_public_key_path must be a readable OpenSSH public key file (ssh-ed25519, ssh-rsa or ecdsa-sha2-*)."
+  }
+}
+
+variable "generate_ssh_key" {
+  description = "Generate an ed25519 key pair instead of using ssh_public_key or ssh_public_key_path. The private key is kept as ssh_private_key_storage says"
+  type        = bool
+  default     = false
+}
+
+variable "ssh_private_key_storage" {
+  description = "Where a generated private key is kept: local_file (ssh_private_key_path, mode 0600) or secrets_manager"
   type        = string
-  default     = "~/.ssh/id_rsa.rfomerand.github.pub"
+  default     = "local_file"
+
+  validation {
+    condition     = contains(["local_file", "secrets_manager"], var.ssh_private_key_storage)
+    error_message = "ssh_private_key_storage must be one of: local_file, secrets_manager."
+  }
 }
 
- variable "ssh_private_key_path" {
-  description = "Path to the public SSH key for EC2 instance access"
+variable "ssh_private_key_path" {
+  description = "Path to the private SSH key used in the ssh outputs. A generated key is written here, defaulting to <name_prefix>-ssh.pem in the root module directory"
   type        = string
-  default     = "~/.ssh/id_rsa.rfomerand.github"
+  default     = ""
 }
 
 variable "github_token" {
   description = "GitHub Personal Access Token for repository access"
   type        = string
   sensitive   = true
+
+  validation {
+    condition     = can(regex("^(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})$", var.github_token))
+    error_message = "github_token must be a GitHub token (ghp_..., github_pat_... or another gh*_ token)."
+  }
+}
+
+# x86_64 families only: the default AMI and bootstrap script are amd64. The
+# suffixes cover the local disk, network, high-frequency and flex variants
+locals {
+  x86_64_instance_type = "^(t3a?|[cmr][5-7][ai]?(b|d|n|dn|z|zn|-flex)?|x2i(e)?dn|g4dn|g5|g6e?|p4d|p5)\\.(nano|micro|small|medium|large|[0-9]*xlarge|metal(-[0-9]+xl)?)$"
 }
 
 variable "instance_type" {
   description = "EC2 instance type for the application"
   type        = string
   default     = "r6i.metal"  # Change this to the instance model, which makes the most sense for you. This one's kind of expensive. 
+
+  validation {
+    condition     = can(regex(local.x86_64_instance_type, var.instance_type))
+    error_message = "instance_type must be an x86_64 instance type from the t3, c5-c7, m5-m7, r5-r7, x2idn, x2iedn, g4dn, g5, g6, p4d or p5 families."
+  }
+}
+
+variable "ollama_model" {
+  description = "Ollama model pulled onto the instance after deployment"
+  type        = string
+  default     = "deepseek-r1:671b"
+}
+
+variable "ollama_keep_alive" {
+  description = "How long Ollama keeps an idle model in memory (OLLAMA_KEEP_ALIVE), e.g. 30m or 24h; -1 keeps it loaded forever"
+  type        = string
+  default     = "-1"
+
+  validation {
+    condition     = can(regex("^(-?[0-9]+|([0-9]+(ms|s|m|h))+)$", var.ollama_keep_alive))
+    error_message = "ollama_keep_alive must be a duration such as 30m or 1h30m, or a number of seconds (-1 for forever)."
+  }
+}
+
+variable "ollama_num_parallel" {
+  description = "Requests each loaded model serves concurrently (OLLAMA_NUM_PARALLEL). Every slot reserves its own context memory"
+  type        = number
+  default     = 1
+}
+
+variable "ollama_max_loaded_models" {
+  description = "Models Ollama may hold in memory at once (OLLAMA_MAX_LOADED_MODELS)"
+  type        = number
+  default     = 1
+}
+
+variable "ollama_context_length" {
+  description = "Default context window in tokens (OLLAMA_CONTEXT_LENGTH)"
+  type        = number
+  default     = 8192
+}
+
+variable "ollama_num_threads" {
+  description = "CPU threads used for inference, set as the num_thread default of the served models. 0 uses every core reported by nproc"
+  type        = number
+  default     = 0
+}
+
+variable "ollama_warmup_models" {
+  description = "Models loaded into memory after the pull, before the deployment is reported ready. Models other than ollama_model are pulled first"
+  type        = list(string)
+  default     = []
+}
+
+variable "enable_api_proxy" {
+  description = "Put an authenticating Caddy reverse proxy in front of the Ollama API and bind Ollama to localhost"
+  type        = bool
+  default     = false
+}
+
+variable "api_proxy_port" {
+  description = "Port the Ollama API proxy listens on"
+  type        = number
+  default     = 443
+}
+
+variable "api_proxy_tls_mode" {
+  description = "How the API proxy obtains its certificate: self_signed or acme"
+  type        = string
+  default     = "self_signed"
+
+  validation {
+    condition     = contains(["self_signed", "acme"], var.api_proxy_tls_mode)
+    error_message = "api_proxy_tls_mode must be one of: self_signed, acme."
+  }
+}
+
+variable "api_proxy_domain" {
+  description = "Hostname served by the API proxy (required for acme, added as a SAN for self_signed)"
+  type        = string
+  default     = ""
+}
+
+variable "api_proxy_acme_email" {
+  description = "Contact email registered with the ACME CA"
+  type        = string
+  default     = ""
+}
+
+variable "api_proxy_auth_mode" {
+  description = "Authentication enforced by the API proxy: bearer or basic"
+  type        = string
+  default     = "bearer"
+
+  validation {
+    condition     = contains(["bearer", "basic"], var.api_proxy_auth_mode)
+    error_message = "api_proxy_auth_mode must be one of: bearer, basic."
+  }
+}
+
+variable "api_proxy_basic_auth_user" {
+  description = "Username accepted by the API proxy when api_proxy_auth_mode is basic"
+  type        = string
+  default     = "ollama"
+}
+
+variable "api_proxy_secret_arn" {
+  description = "Existing Secrets Manager secret holding the API token/password. A secret is generated when empty"
+  type        = string
+  default     = ""
+}
+
+variable "create_model_cache_bucket" {
+  description = "Create an S3 bucket to cache Ollama blobs and manifests between instances"
+  type        = bool
+  default     = false
+}
+
+variable "model_cache_bucket" {
+  description = "Existing S3 bucket used as the Ollama model cache. Share one bucket across deployments to avoid re-pulling from the registry"
+  type        = string
+  default     = ""
+}
+
+variable "model_cache_prefix" {
+  description = "Key prefix under which the model cache is stored in the bucket"
+  type        = string
+  default     = "ollama/models"
+}
+
+variable "model_cache_force_destroy" {
+  description = "Allow destroying a created model cache bucket that still holds models"
+  type        = bool
+  default     = false
+}
+
+variable "model_volume_size" {
+  description = "Size in GiB of a dedicated EBS volume for the Ollama model store. 0 keeps models on the root volume unless a snapshot is given, in which case the snapshot size is used"
+  type        = number
+  default     = 0
+
+  validation {
+    condition     = var.model_volume_size >= 0
+    error_message = "model_volume_size must not be negative."
+  }
+}
+
+variable "model_volume_snapshot_id" {
+  description = "EBS snapshot holding a fully pulled Ollama model store to create the model volume from"
+  type        = string
+  default     = ""
+}
+
+variable "model_volume_snapshot_tags" {
+  description = "Tags identifying model store snapshots owned by this account; the newest match is used when model_volume_snapshot_id is empty"
+  type        = map(string)
+  default     = {}
+}
+
+variable "model_volume_fast_snapshot_restore" {
+  description = "Enable Fast Snapshot Restore for the model snapshot in the instance's availability zone"
+  type        = bool
+  default     = false
+}
+
+variable "model_volume_throughput" {
+  description = "Provisioned throughput of the gp3 model volume in MiB/s"
+  type        = number
+  default     = 500
+
+  validation {
+    condition     = var.model_volume_throughput >= 125 && var.model_volume_throughput <= 1000
+    error_message = "model_volume_throughput must be between 125 and 1000 MiB/s."
+  }
+}
+
+variable "enable_autoscaling" {
+  description = "Run the instance in an Auto Scaling group that replaces it when it fails health checks, instead of a standalone aws_instance"
+  type        = bool
+  default     = false
+}
+
+variable "asg_min_size" {
+  description = "Minimum (and desired) number of instances in the Auto Scaling group"
+  type        = number
+  default     = 1
+}
+
+variable "asg_max_size" {
+  description = "Maximum number of instances in the Auto Scaling group"
+  type        = number
+  default     = 1
+}
+
+variable "asg_health_check_type" {
+  description = "Health check the Auto Scaling group replaces instances on. Only EC2 status checks are supported: the group is not behind a load balancer, so ELB health would never be reported"
+  type        = string
+  default     = "EC2"
+
+  validation {
+    condition     = var.asg_health_check_type == "EC2"
+    error_message = "asg_health_check_type must be EC2; the Auto Scaling group has no load balancer to report ELB health."
+  }
+}
+
+variable "asg_health_check_grace_period" {
+  description = "Seconds after launch before health checks count, long enough for the bootstrap to finish"
+  type        = number
+  default     = 1800
+}
+
+variable "create_eip" {
+  description = "Allocate an Elastic IP so the public address survives instance replacement"
+  type        = bool
+  default     = false
+}
+
+variable "replica_count" {
+  description = "Number of Ollama-only inference instances behind an internal load balancer. 0 runs Ollama on the OpenWebUI instance"
+  type        = number
+  default     = 0
+
+  validation {
+    condition     = var.replica_count >= 0 && floor(var.replica_count) == var.replica_count
+    error_message = "replica_count must be a whole number of instances."
+  }
+}
+
+variable "inference_instance_type" {
+  description = "EC2 instance type of the inference pool. Defaults to instance_type"
+  type        = string
+  default     = ""
+
+  # Same families as instance_type
+  validation {
+    condition     = var.inference_instance_type == "" || can(regex(local.x86_64_instance_type, var.inference_instance_type))
+    error_message = "inference_instance_type must be an x86_64 instance type from the t3, c5-c7, m5-m7, r5-r7, x2idn, x2iedn, g4dn, g5, g6, p4d or p5 families."
+  }
+}
+
+variable "inference_lb_type" {
+  description = "Load balancer in front of the inference pool: network or application"
+  type        = string
+  default     = "network"
+
+  validation {
+    condition     = contains(["network", "application"], var.inference_lb_type)
+    error_message = "inference_lb_type must be one of: network, application."
+  }
+}
+
+variable "inference_sticky_sessions" {
+  description = "Pin clients to one inference instance (source IP for network, cookie for application load balancers) so follow-up requests reuse its loaded context"
+  type        = bool
+  default     = false
+}
+
+variable "route53_zone_id" {
+  description = "Route 53 hosted zone in which to create A records for dns_hostname and api_proxy_domain. No records are managed when empty"
+  type        = string
+  default     = ""
+}
+
+variable "dns_hostname" {
+  description = "Fully qualified hostname for the instance, used in the OpenWebUI, Ollama and SSH outputs. Pair with create_eip for an address that survives rebuilds"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.dns_hostname == "" || can(regex("^([a-z0-9]([a-z0-9-]*[a-z0-9])?\\.)+[a-z]{2,}$", var.dns_hostname))
+    error_message = "dns_hostname must be a lowercase fully qualified domain name such as ollama.example.com."
+  }
+}
+
+variable "log_retention_days" {
+  description = "Days to keep each class of logs: deploy (bootstrap and application) and model_pull. 0 keeps them forever"
+  type = object({
+    deploy     = optional(number, 30)
+    model_pull = optional(number, 30)
+  })
+  default = {}
+
+  validation {
+    condition = alltrue([
+      for days in values(var.log_retention_days) :
+      contains([0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], days)
+    ])
+    error_message = "log_retention_days values must be retention periods CloudWatch Logs supports, such as 7, 30, 90 or 365, or 0."
+  }
+}
+
+variable "notification_emails" {
+  description = "Email addresses subscribed to the SNS topic the error alarms notify. Each address must confirm its subscription"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for email in var.notification_emails : can(regex("^[^@\\s]+@[^@\\s]+\\.[^@\\s]+$", email))])
+    error_message = "notification_emails must be email addresses."
+  }
+}
+
+variable "error_alarm_threshold" {
+  description = "Error events within error_alarm_period that raise the deploy and model pull error alarms"
+  type        = number
+  default     = 1
+
+  validation {
+    condition     = var.error_alarm_threshold >= 1
+    error_message = "error_alarm_threshold must be at least 1."
+  }
+}
+
+variable "error_alarm_period" {
+  description = "Seconds over which error events are summed for the error alarms"
+  type        = number
+  default     = 300
+
+  validation {
+    condition     = var.error_alarm_period >= 60 && var.error_alarm_period % 60 == 0
+    error_message = "error_alarm_period must be a multiple of 60 seconds."
+  }
 }